@@ -0,0 +1,57 @@
+package jsonhttp
+
+import (
+  "sort"
+  "strconv"
+  "strings"
+)
+
+// qualityValue is one element of an Accept style header, such as
+// "application/json;version=2;q=0.8".
+type qualityValue struct {
+  value string
+  params map[string]string
+  q float64
+}
+
+type qualityValues []qualityValue
+
+func (a qualityValues) Len() int { return len(a) }
+func (a qualityValues) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a qualityValues) Less(i, j int) bool { return a[i].q > a[j].q }
+
+// parseQualityList parses a comma separated header into its values, ordered
+// by descending q. Values with q=0 are kept so callers can treat them as
+// explicitly refused.
+func parseQualityList(header string) []qualityValue {
+  var values qualityValues
+  for _, part := range strings.Split(header, ",") {
+    fields := strings.Split(part, ";")
+    value := strings.ToLower(strings.TrimSpace(fields[0]))
+    if len(value) == 0 {
+      continue
+    }
+    qv := qualityValue{value: value, q: 1.0}
+    for _, param := range fields[1:] {
+      kv := strings.SplitN(param, "=", 2)
+      key := strings.ToLower(strings.TrimSpace(kv[0]))
+      val := ""
+      if len(kv) > 1 {
+        val = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+      }
+      if key == "q" {
+        if q, err := strconv.ParseFloat(val, 64); err == nil {
+          qv.q = q
+        }
+        continue
+      }
+      if qv.params == nil {
+        qv.params = make(map[string]string)
+      }
+      qv.params[key] = val
+    }
+    values = append(values, qv)
+  }
+  sort.Stable(values)
+  return values
+}
@@ -0,0 +1,206 @@
+package jsonhttp
+
+import (
+  "compress/gzip"
+  "compress/zlib"
+  "io"
+  "net/http"
+  "strings"
+)
+
+const (
+  encodingGzip = "gzip"
+  encodingDeflate = "deflate"
+)
+
+var DefaultCompressMinSize = 1024
+
+var uncompressibleTypes = []string{
+  "image/",
+  "video/",
+  "audio/",
+  "font/woff",
+  "application/zip",
+  "application/gzip",
+  "application/x-gzip",
+  "application/x-bzip2",
+  "application/x-7z-compressed",
+  "application/x-rar-compressed",
+}
+
+// CompressHandler compresses the response of H with gzip or deflate,
+// negotiated from the Accept-Encoding header. Bodies smaller than MinSize, of
+// an already compressed content type, or which already have a
+// Content-Encoding are written unchanged. A zero MinSize or Level means
+// DefaultCompressMinSize or gzip.DefaultCompression. Wrap synchttp.Handlers
+// with a CompressHandler, rather than each of its handlers, so that only the
+// winning response is compressed.
+type CompressHandler struct {
+  H http.Handler
+  MinSize int
+  Level int
+}
+
+func Compress(h http.Handler) http.Handler {
+  return &CompressHandler{H: h}
+}
+
+func (h *CompressHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+  encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"))
+  cw := &compressWriter{ResponseWriter: w, minSize: h.MinSize, level: h.Level, encoding: encoding, code: http.StatusOK}
+  if cw.minSize == 0 {
+    cw.minSize = DefaultCompressMinSize
+  }
+  if cw.level == 0 {
+    cw.level = gzip.DefaultCompression
+  }
+  defer cw.close()
+  h.H.ServeHTTP(cw, req)
+}
+
+func negotiateEncoding(header string) string {
+  refused := make(map[string]bool)
+  values := parseQualityList(header)
+  for _, qv := range values {
+    if qv.q <= 0 {
+      refused[qv.value] = true
+    }
+  }
+  for _, qv := range values {
+    if qv.q <= 0 {
+      continue
+    }
+    switch qv.value {
+      case encodingGzip, "x-" + encodingGzip:
+        return encodingGzip
+      case encodingDeflate:
+        return encodingDeflate
+      case "*":
+        if !refused[encodingGzip] {
+          return encodingGzip
+        }
+        if !refused[encodingDeflate] {
+          return encodingDeflate
+        }
+    }
+  }
+  return ""
+}
+
+func addVary(header http.Header, value string) {
+  for _, vary := range header["Vary"] {
+    for _, v := range strings.Split(vary, ",") {
+      if strings.EqualFold(strings.TrimSpace(v), value) {
+        return
+      }
+    }
+  }
+  header.Add("Vary", value)
+}
+
+func isCompressibleType(contentType string) bool {
+  contentType = strings.ToLower(contentType)
+  for _, t := range uncompressibleTypes {
+    if strings.HasPrefix(contentType, t) {
+      return false
+    }
+  }
+  return true
+}
+
+type compressWriter struct {
+  http.ResponseWriter
+  minSize int
+  level int
+  encoding string
+  code int
+  buf []byte
+  decided bool
+  enc io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+  if cw.decided {
+    return
+  }
+  cw.code = code
+  if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+    cw.decide(false)
+  }
+}
+
+func (cw *compressWriter) Write(content []byte) (int, error) {
+  if !cw.decided {
+    cw.buf = append(cw.buf, content...)
+    if len(cw.buf) >= cw.minSize {
+      if err := cw.decide(true); err != nil {
+        return 0, err
+      }
+    }
+    return len(content), nil
+  }
+  if cw.enc != nil {
+    return cw.enc.Write(content)
+  }
+  return cw.ResponseWriter.Write(content)
+}
+
+// Flush compresses whatever has been buffered so far, regardless of size,
+// because a flushing handler is streaming and its final size is unknown.
+func (cw *compressWriter) Flush() {
+  if !cw.decided {
+    cw.decide(true)
+  }
+  if f, ok := cw.enc.(interface{ Flush() error }); ok {
+    f.Flush()
+  }
+  if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+    f.Flush()
+  }
+}
+
+// decide adds Vary only once the handler's headers are final, because a
+// synchttp.Handlers response replaces any header it also sets.
+func (cw *compressWriter) decide(large bool) error {
+  cw.decided = true
+  header := cw.Header()
+  addVary(header, "Accept-Encoding")
+  if len(header.Get("Content-Type")) == 0 && len(cw.buf) > 0 {
+    header.Set("Content-Type", http.DetectContentType(cw.buf))
+  }
+  if large && len(cw.encoding) > 0 && len(header.Get("Content-Encoding")) == 0 && isCompressibleType(header.Get("Content-Type")) {
+    header.Set("Content-Encoding", cw.encoding)
+    header.Del("Content-Length")
+    switch cw.encoding {
+      case encodingGzip:
+        enc, err := gzip.NewWriterLevel(cw.ResponseWriter, cw.level)
+        if err != nil {
+          return err
+        }
+        cw.enc = enc
+      case encodingDeflate:
+        enc, err := zlib.NewWriterLevel(cw.ResponseWriter, cw.level)
+        if err != nil {
+          return err
+        }
+        cw.enc = enc
+    }
+  }
+  cw.ResponseWriter.WriteHeader(cw.code)
+  buf := cw.buf
+  cw.buf = nil
+  if len(buf) == 0 {
+    return nil
+  }
+  _, err := cw.Write(buf)
+  return err
+}
+
+func (cw *compressWriter) close() {
+  if !cw.decided {
+    cw.decide(len(cw.buf) >= cw.minSize)
+  }
+  if cw.enc != nil {
+    cw.enc.Close()
+  }
+}
@@ -2,11 +2,18 @@ package jsonhttp_test
 
 import (
   "github.com/istreeter/gotools/jsonhttp"
+  "github.com/istreeter/gotools/synchttp"
   "net/http"
   "net/http/httptest"
   "fmt"
   "time"
   "errors"
+  "context"
+  "encoding/json"
+  "compress/gzip"
+  "compress/zlib"
+  "io/ioutil"
+  "strings"
 )
 
 func ExampleNewErrorHandler() {
@@ -87,3 +94,218 @@ func ExampleHandleWithMsgs() {
   // Second response: 503 - [application/json; charset=UTF-8] - {"error":true,"message":"Server Timeout","name":"Service Unavailable"}
   // Third response: 500 - [application/json; charset=UTF-8] - {"error":true,"message":"Server Error","name":"Internal Server Error"}
 }
+
+func ExampleHealthHandler() {
+
+  ready := jsonhttp.NewHealthHandler()
+  ready.Register("database", 100 * time.Millisecond, true, func(ctx context.Context) error {
+    return nil
+  })
+  ready.Register("cache", 100 * time.Millisecond, false, func(ctx context.Context) error {
+    return errors.New("cache is down")
+  })
+
+  req := httptest.NewRequest("GET", "http://example.com/readyz", nil)
+  w := httptest.NewRecorder()
+  ready.ServeHTTP(w, req)
+
+  res := struct {
+    Status string
+    Checks map[string]struct{ Status, Error string }
+  }{}
+  json.NewDecoder(w.Body).Decode(&res)
+  fmt.Printf("First response: %d - %s - database %s - cache %s (%s)\n",
+    w.Code, res.Status, res.Checks["database"].Status, res.Checks["cache"].Status, res.Checks["cache"].Error)
+
+  ready.Register("mongo", 50 * time.Millisecond, true, func(ctx context.Context) error {
+    time.Sleep(200 * time.Millisecond)
+    return nil
+  })
+
+  w = httptest.NewRecorder()
+  ready.ServeHTTP(w, req)
+  json.NewDecoder(w.Body).Decode(&res)
+  fmt.Printf("Second response: %d - %s - mongo %s (%s)\n",
+    w.Code, res.Status, res.Checks["mongo"].Status, res.Checks["mongo"].Error)
+
+  fmt.Println(ready.Register("mongo", time.Second, false, func(ctx context.Context) error {
+    return nil
+  }))
+
+  // Output:
+  // First response: 200 - ok - database ok - cache fail (cache is down)
+  // Second response: 503 - fail - mongo fail (Health check timed out)
+  // Health check mongo is already registered
+}
+
+func ExampleLocalizedError() {
+
+  jsonhttp.DefaultCatalog.Set("bad_date", "en", "The date is invalid")
+  jsonhttp.DefaultCatalog.Set("bad_date", "fr", "La date est invalide")
+  jsonhttp.DefaultCatalog.Set("bad_date", "pt-BR", "A data é inválida")
+
+  handler := func(w http.ResponseWriter, req *http.Request) {
+    jsonhttp.LocalizedError(w, req, "bad_date", http.StatusBadRequest)
+  }
+
+  for _, accept := range []string{"fr-CA, en;q=0.8", "de, pt-BR;q=0.5", "de"} {
+    req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+    req.Header.Set("Accept-Language", accept)
+    w := httptest.NewRecorder()
+    handler(w, req)
+    fmt.Printf("%d - %s - %s", w.Code, w.HeaderMap["Content-Language"], w.Body.String())
+  }
+
+  // Output:
+  // 400 - [fr] - {"error":true,"message":"La date est invalide","name":"Bad Request"}
+  // 400 - [pt-br] - {"error":true,"message":"A data é inválida","name":"Bad Request"}
+  // 400 - [en] - {"error":true,"message":"The date is invalid","name":"Bad Request"}
+}
+
+func ExampleNewErrorHandler_localized() {
+
+  errorHandler := jsonhttp.NewErrorHandler("Page not found", http.StatusNotFound)
+  jsonhttp.DefaultCatalog.Set("Page not found", "de", "Seite nicht gefunden")
+  jsonhttp.DefaultCatalog.Set("Page gone", "fr", "Page disparue")
+
+  for _, accept := range []string{"", "de-AT", "fr"} {
+    req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+    req.Header.Set("Accept-Language", accept)
+    w := httptest.NewRecorder()
+    errorHandler.ServeHTTP(w, req)
+    fmt.Printf("%d - %s - %s", w.Code, w.HeaderMap["Content-Language"], w.Body.String())
+  }
+
+  // Output:
+  // 404 - [en] - {"error":true,"message":"Page not found","name":"Not Found"}
+  // 404 - [de] - {"error":true,"message":"Seite nicht gefunden","name":"Not Found"}
+  // 404 - [en] - {"error":true,"message":"Page not found","name":"Not Found"}
+}
+
+func ExampleCompress() {
+
+  data := map[string]interface{}{"text": strings.Repeat("All work and no play makes Jack a dull boy. ", 100)}
+
+  goodHandler := func(w http.ResponseWriter, req *http.Request) {
+    jsonhttp.OK(w, data)
+  }
+
+  handler := jsonhttp.Compress(jsonhttp.HandleWithMsgs(http.HandlerFunc(goodHandler), 100 * time.Millisecond))
+
+  req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+  req.Header.Set("Accept-Encoding", "deflate;q=0.5, gzip")
+  w := httptest.NewRecorder()
+  handler.ServeHTTP(w, req)
+
+  zr, err := gzip.NewReader(w.Body)
+  if err != nil {
+    panic(err)
+  }
+  body, _ := ioutil.ReadAll(zr)
+  fmt.Printf("First response: %d - %s - %s - %d bytes decompressed\n",
+    w.Code, w.HeaderMap["Content-Encoding"], w.HeaderMap["Vary"], len(body))
+
+  req = httptest.NewRequest("GET", "http://example.com/foo", nil)
+  req.Header.Set("Accept-Encoding", "identity")
+  w = httptest.NewRecorder()
+  handler.ServeHTTP(w, req)
+  fmt.Printf("Second response: %d - %s - %s - %d bytes\n",
+    w.Code, w.HeaderMap["Content-Encoding"], w.HeaderMap["Vary"], w.Body.Len())
+
+  // Output:
+  // First response: 200 - [gzip] - [Accept-Encoding] - 4412 bytes decompressed
+  // Second response: 200 - [] - [Accept-Encoding] - 4412 bytes
+}
+
+func ExampleCompressHandler() {
+
+  handler := &jsonhttp.CompressHandler{H: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+    jsonhttp.OK(w, map[string]string{"text": strings.Repeat(req.URL.Query().Get("text"), 100)})
+  })}
+
+  req := httptest.NewRequest("GET", "http://example.com/foo?text=All+work+and+no+play+makes+Jack+a+dull+boy.+", nil)
+  req.Header.Set("Accept-Encoding", "gzip")
+  w := httptest.NewRecorder()
+  handler.ServeHTTP(w, req)
+  fmt.Printf("First response: %s - under 1kB: %t\n", w.HeaderMap["Content-Encoding"], w.Body.Len() < 1024)
+
+  req = httptest.NewRequest("GET", "http://example.com/foo?text=a", nil)
+  req.Header.Set("Accept-Encoding", "gzip")
+  w = httptest.NewRecorder()
+  handler.ServeHTTP(w, req)
+  fmt.Printf("Second response: %s - %d bytes\n", w.HeaderMap["Content-Encoding"], w.Body.Len())
+
+  // Output:
+  // First response: [gzip] - under 1kB: true
+  // Second response: [] - 112 bytes
+}
+
+func ExampleCompressHandler_handlers() {
+
+  localized := func(w http.ResponseWriter, req *http.Request) {
+    w.Header().Set("Vary", "Accept-Language")
+    jsonhttp.OK(w, map[string]string{"text": strings.Repeat("Tout travail et pas de jeu. ", 100)})
+  }
+
+  handler := jsonhttp.Compress(synchttp.Handlers{http.HandlerFunc(localized)})
+
+  req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+  req.Header.Set("Accept-Encoding", "deflate")
+  w := httptest.NewRecorder()
+  handler.ServeHTTP(w, req)
+
+  zr, err := zlib.NewReader(w.Body)
+  if err != nil {
+    panic(err)
+  }
+  body, _ := ioutil.ReadAll(zr)
+  fmt.Printf("%d - %s - %s - %d bytes decompressed\n",
+    w.Code, w.HeaderMap["Content-Encoding"], w.HeaderMap["Vary"], len(body))
+
+  // Output:
+  // 200 - [deflate] - [Accept-Language Accept-Encoding] - 2812 bytes decompressed
+}
+
+func ExampleVersionHandler() {
+
+  v1 := func(w http.ResponseWriter, req *http.Request) {
+    jsonhttp.OK(w, map[string]interface{}{"id": 10, "tweet": map[string]interface{}{"id": 10, "text": "hello"}})
+  }
+  v2 := func(w http.ResponseWriter, req *http.Request) {
+    jsonhttp.OK(w, map[string]interface{}{"id": 10, "text": "hello", "path": req.URL.EscapedPath()})
+  }
+
+  handler := &jsonhttp.VersionHandler{
+    Versions: map[string]*jsonhttp.APIVersion{
+      "1": {H: http.HandlerFunc(v1), Sunset: time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)},
+      "2": {H: http.HandlerFunc(v2)},
+    },
+    Default: "1",
+    MediaType: "application/vnd.gotools+json",
+    Header: "X-API-Version",
+    PathPrefix: true,
+  }
+
+  requests := []*http.Request{
+    httptest.NewRequest("GET", "http://example.com/tweets/10", nil),
+    httptest.NewRequest("GET", "http://example.com/tweets/10", nil),
+    httptest.NewRequest("GET", "http://example.com/v2/tweets/10", nil),
+    httptest.NewRequest("GET", "http://example.com/tweets/10", nil),
+    httptest.NewRequest("GET", "http://example.com/v2/tags/a%2Fb", nil),
+  }
+  requests[1].Header.Set("Accept", "application/vnd.gotools+json; version=2")
+  requests[3].Header.Set("X-API-Version", "3")
+
+  for _, req := range requests {
+    w := httptest.NewRecorder()
+    handler.ServeHTTP(w, req)
+    fmt.Printf("%d - %s - %s", w.Code, w.HeaderMap["Sunset"], w.Body.String())
+  }
+
+  // Output:
+  // 200 - [Mon, 01 Jan 2018 00:00:00 GMT] - {"id":10,"tweet":{"id":10,"text":"hello"}}
+  // 200 - [] - {"id":10,"path":"/tweets/10","text":"hello"}
+  // 200 - [] - {"id":10,"path":"/tweets/10","text":"hello"}
+  // 406 - [] - {"error":true,"message":"Unknown API version: 3","name":"Not Acceptable"}
+  // 200 - [] - {"id":10,"path":"/tags/a%2Fb","text":"hello"}
+}
@@ -0,0 +1,140 @@
+package jsonhttp
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "net/http"
+  "sync"
+  "time"
+)
+
+const (
+  HealthStatusOK = "ok"
+  HealthStatusFail = "fail"
+)
+
+var DefaultHealthCheckTimeout = 5 * time.Second
+
+var ErrHealthCheckTimeout = errors.New("Health check timed out")
+
+type HealthCheckFunc func(ctx context.Context) error
+
+type HealthCheck struct {
+  Name string
+  Check HealthCheckFunc
+  Timeout time.Duration
+  Critical bool
+}
+
+// HealthHandler runs its checks concurrently on every request and responds
+// with a JSON summary. The response code is 503 if any critical check fails,
+// so the same type serves both liveness (/healthz) and readiness (/readyz).
+type HealthHandler struct {
+  mu sync.RWMutex
+  checks []*HealthCheck
+}
+
+// NewHealthHandler panics if two checks have the same name.
+func NewHealthHandler(checks ...*HealthCheck) *HealthHandler {
+  h := &HealthHandler{}
+  for _, c := range checks {
+    if err := h.add(c); err != nil {
+      panic(err)
+    }
+  }
+  return h
+}
+
+// Register adds a check, or returns an error if a check of the same name is
+// already registered, because each name has one entry in the response.
+func (h *HealthHandler) Register(name string, timeout time.Duration, critical bool, check HealthCheckFunc) error {
+  return h.add(&HealthCheck{Name: name, Check: check, Timeout: timeout, Critical: critical})
+}
+
+func (h *HealthHandler) add(c *HealthCheck) error {
+  h.mu.Lock()
+  defer h.mu.Unlock()
+  for _, existing := range h.checks {
+    if existing.Name == c.Name {
+      return fmt.Errorf("Health check %s is already registered", c.Name)
+    }
+  }
+  h.checks = append(h.checks, c)
+  return nil
+}
+
+type healthResponse struct {
+  Status string `json:"status"`
+  Checks map[string]*healthCheckResult `json:"checks"`
+}
+
+type healthCheckResult struct {
+  Status string `json:"status"`
+  Critical bool `json:"critical"`
+  Latency string `json:"latency"`
+  Error string `json:"error,omitempty"`
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+  h.mu.RLock()
+  checks := make([]*HealthCheck, len(h.checks))
+  copy(checks, h.checks)
+  h.mu.RUnlock()
+
+  results := make([]*healthCheckResult, len(checks))
+  var wg sync.WaitGroup
+  wg.Add(len(checks))
+  for i, c := range checks {
+    go func(i int, c *HealthCheck) {
+      defer wg.Done()
+      results[i] = c.run(req.Context())
+    }(i, c)
+  }
+  wg.Wait()
+
+  res := &healthResponse{Status: HealthStatusOK, Checks: make(map[string]*healthCheckResult)}
+  code := http.StatusOK
+  for i, c := range checks {
+    res.Checks[c.Name] = results[i]
+    if results[i].Status != HealthStatusOK && c.Critical {
+      res.Status = HealthStatusFail
+      code = http.StatusServiceUnavailable
+    }
+  }
+  write(w, res, code)
+}
+
+func (c *HealthCheck) run(parent context.Context) *healthCheckResult {
+  timeout := c.Timeout
+  if timeout <= 0 {
+    timeout = DefaultHealthCheckTimeout
+  }
+  ctx, cancel := context.WithTimeout(parent, timeout)
+  defer cancel()
+
+  start := time.Now()
+  errCh := make(chan error, 1)
+  go func() {
+    defer func() {
+      if r := recover(); r != nil {
+        errCh <- fmt.Errorf("Health check panicked: %v", r)
+      }
+    }()
+    errCh <- c.Check(ctx)
+  }()
+
+  var err error
+  select {
+    case err = <-errCh:
+    case <-ctx.Done():
+      err = ErrHealthCheckTimeout
+  }
+
+  res := &healthCheckResult{Status: HealthStatusOK, Critical: c.Critical, Latency: time.Since(start).String()}
+  if err != nil {
+    res.Status = HealthStatusFail
+    res.Error = err.Error()
+  }
+  return res
+}
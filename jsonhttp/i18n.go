@@ -0,0 +1,112 @@
+package jsonhttp
+
+import (
+  "net/http"
+  "strings"
+  "sync"
+)
+
+var DefaultCatalog = NewCatalog("en")
+
+// Catalog holds messages keyed by message ID and language. A message ID with
+// no entry in the catalog is used as the message itself, so plain English
+// messages work without any catalog setup.
+type Catalog struct {
+  mu sync.RWMutex
+  defaultLang string
+  messages map[string]map[string]string
+  version int
+}
+
+func NewCatalog(defaultLang string) *Catalog {
+  return &Catalog{
+    defaultLang: normalizeLang(defaultLang),
+    messages: make(map[string]map[string]string),
+  }
+}
+
+func (c *Catalog) DefaultLanguage() string {
+  return c.defaultLang
+}
+
+func (c *Catalog) Set(id string, lang string, message string) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  lang = normalizeLang(lang)
+  if c.messages[id] == nil {
+    c.messages[id] = make(map[string]string)
+  }
+  c.messages[id][lang] = message
+  c.version++
+}
+
+// Message returns the message for id in lang, falling back to the base
+// language (en-GB to en), then the default language, then id itself.
+func (c *Catalog) Message(id string, lang string) string {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  msgs := c.messages[id]
+  lang = normalizeLang(lang)
+  if msg, ok := msgs[lang]; ok {
+    return msg
+  }
+  if msg, ok := msgs[baseLang(lang)]; ok {
+    return msg
+  }
+  if msg, ok := msgs[c.defaultLang]; ok {
+    return msg
+  }
+  return id
+}
+
+// Negotiate picks the best language with a message for id from the
+// request's Accept-Language header, or the default language if nothing
+// matches.
+func (c *Catalog) Negotiate(req *http.Request, id string) string {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  msgs := c.messages[id]
+  for _, lang := range parseAcceptLanguage(req.Header.Get("Accept-Language")) {
+    if lang == "*" {
+      return c.defaultLang
+    }
+    if _, ok := msgs[lang]; ok {
+      return lang
+    }
+    if _, ok := msgs[baseLang(lang)]; ok {
+      return baseLang(lang)
+    }
+  }
+  return c.defaultLang
+}
+
+func (c *Catalog) Localize(req *http.Request, id string) string {
+  return c.Message(id, c.Negotiate(req, id))
+}
+
+func (c *Catalog) currentVersion() int {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  return c.version
+}
+
+func parseAcceptLanguage(header string) []string {
+  var langs []string
+  for _, qv := range parseQualityList(header) {
+    if qv.q > 0 {
+      langs = append(langs, normalizeLang(qv.value))
+    }
+  }
+  return langs
+}
+
+func normalizeLang(lang string) string {
+  return strings.ToLower(strings.Replace(strings.TrimSpace(lang), "_", "-", -1))
+}
+
+func baseLang(lang string) string {
+  if i := strings.Index(lang, "-"); i > 0 {
+    return lang[:i]
+  }
+  return lang
+}
@@ -5,6 +5,7 @@ import (
   "net/http"
   "github.com/istreeter/gotools/synchttp"
   "time"
+  "sync"
 )
 
 var DefaultCtxDoneHandler = &synchttp.CtxDoneHandler{H: NewErrorHandler("Server Timeout", http.StatusServiceUnavailable)}
@@ -13,26 +14,64 @@ var DefaultErrorHandler = NewErrorHandler("Server Error", http.StatusInternalSer
 type errorHandler struct{
   content []byte
   code int
+  id string
+  mu sync.Mutex
+  version int
 }
 func (h *errorHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+    lang := DefaultCatalog.Negotiate(req, h.id)
+    content := h.defaultContent()
+    if lang != DefaultCatalog.DefaultLanguage() {
+      content = errorContent(DefaultCatalog.Message(h.id, lang), h.code)
+    }
+    w.Header().Add("Vary", "Accept-Language")
+    w.Header().Set("Content-Language", lang)
     w.Header().Set("Content-Type", "application/json; charset=UTF-8")
     w.WriteHeader(h.code)
-    w.Write(h.content)
+    w.Write(content)
 }
 
+func (h *errorHandler) defaultContent() []byte {
+  h.mu.Lock()
+  defer h.mu.Unlock()
+  if version := DefaultCatalog.currentVersion(); version != h.version {
+    h.content = errorContent(DefaultCatalog.Message(h.id, DefaultCatalog.DefaultLanguage()), h.code)
+    h.version = version
+  }
+  return h.content
+}
+
+// NewErrorHandler responds with message localized by DefaultCatalog for the
+// request's Accept-Language. Output for the default language is
+// precomputed, and only recomputed if the catalog changes.
 func NewErrorHandler(message string, code int) http.Handler {
+  return &errorHandler{
+    content: errorContent(DefaultCatalog.Message(message, DefaultCatalog.DefaultLanguage()), code),
+    code: code,
+    id: message,
+    version: DefaultCatalog.currentVersion(),
+  }
+}
+
+func errorContent(message string, code int) []byte {
   jsonContent, err := json.Marshal(&errorResponse{Error: true, Message: message, Name: http.StatusText(code)})
   if err != nil {
     panic(err)
   }
-  jsonContent = append(jsonContent, "\n"...)
-  return &errorHandler{content: jsonContent, code: code}
+  return append(jsonContent, "\n"...)
 }
 
 type errorResponse struct{
   Error bool `json:"error"`
   Message string `json:"message"`
   Name string    `json:"name"`
+  Errors interface{} `json:"errors,omitempty"`
+}
+
+// Detailer is implemented by errors carrying structured details, such as
+// optshttp.ValidationError, which ErrorFrom renders in an "errors" field.
+type Detailer interface {
+  Details() interface{}
 }
 
 func Error(w http.ResponseWriter, message string, code int) {
@@ -40,6 +79,23 @@ func Error(w http.ResponseWriter, message string, code int) {
   write(w, res, code)
 }
 
+func ErrorFrom(w http.ResponseWriter, err error, code int) {
+  res := &errorResponse{Error: true, Message: err.Error(), Name: http.StatusText(code)}
+  if d, ok := err.(Detailer); ok {
+    res.Errors = d.Details()
+  }
+  write(w, res, code)
+}
+
+// LocalizedError is Error with message localized by DefaultCatalog for the
+// request's Accept-Language. Error itself has no request to negotiate with.
+func LocalizedError(w http.ResponseWriter, req *http.Request, message string, code int) {
+  lang := DefaultCatalog.Negotiate(req, message)
+  w.Header().Add("Vary", "Accept-Language")
+  w.Header().Set("Content-Language", lang)
+  Error(w, DefaultCatalog.Message(message, lang), code)
+}
+
 func write(w http.ResponseWriter, content interface{}, code int) {
   w.Header().Set("Content-Type", "application/json; charset=UTF-8")
   w.WriteHeader(code)
@@ -0,0 +1,65 @@
+package jsonhttptest_test
+
+import (
+  "github.com/istreeter/gotools/jsonhttp"
+  "github.com/istreeter/gotools/jsonhttp/jsonhttptest"
+  "net/http"
+  "fmt"
+)
+
+type printT struct{}
+
+func (printT) Errorf(format string, args ...interface{}) { fmt.Printf(format + "\n", args...) }
+func (printT) Fatalf(format string, args ...interface{}) { fmt.Printf(format + "\n", args...) }
+
+func ExampleResponse_JSON() {
+
+  handler := func(w http.ResponseWriter, req *http.Request) {
+    data := map[string]interface{}{
+      "status": "good",
+      "updated": "2017-03-01T10:00:00Z",
+      "labels": []string{"go", "http"},
+    }
+    jsonhttp.OK(w, data)
+  }
+
+  var t printT
+
+  jsonhttptest.Get(t, http.HandlerFunc(handler), "http://example.com/foo").
+    Status(http.StatusOK).
+    ContentTypeJSON().
+    JSON(`{"labels": ["go", "http"], "status": "good"}`, "updated")
+
+  jsonhttptest.Get(t, http.HandlerFunc(handler), "http://example.com/foo").
+    JSON(map[string]interface{}{"status": "bad", "labels": []string{"go", "json"}, "count": 1}, "updated")
+
+  // Output:
+  // JSON body differs:
+  // $.count: missing, expected 1
+  // $.labels.1: expected "json", got "http"
+  // $.status: expected "bad", got "good"
+}
+
+func ExampleResponse_Error() {
+
+  handler := jsonhttp.NewErrorHandler("You made an error", http.StatusBadRequest)
+
+  var t printT
+
+  jsonhttptest.Get(t, handler, "http://example.com/foo").
+    Error(http.StatusBadRequest, "You made an error")
+
+  body := jsonhttptest.Get(t, handler, "http://example.com/foo").ErrorBody()
+  fmt.Println(body.Name, "-", body.Message)
+
+  jsonhttptest.Get(t, handler, "http://example.com/foo").
+    Error(http.StatusNotFound, "Not found")
+
+  // Output:
+  // Bad Request - You made an error
+  // status: expected 404 Not Found, got 400 Bad Request
+  // body: {"error":true,"message":"You made an error","name":"Bad Request"}
+  //
+  // error message: expected "Not found", got "You made an error"
+  // error name: expected "Not Found", got "Bad Request"
+}
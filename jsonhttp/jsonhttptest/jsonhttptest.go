@@ -0,0 +1,262 @@
+package jsonhttptest
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "net/http"
+  "net/http/httptest"
+  "sort"
+  "strings"
+)
+
+// T is the subset of testing.TB used for reporting, so that *testing.T and
+// *testing.B can be passed directly.
+type T interface {
+  Errorf(format string, args ...interface{})
+  Fatalf(format string, args ...interface{})
+}
+
+type helper interface {
+  Helper()
+}
+
+// ErrorBody is the body written by jsonhttp.Error and jsonhttp.NewErrorHandler.
+type ErrorBody struct {
+  Error bool `json:"error"`
+  Message string `json:"message"`
+  Name string `json:"name"`
+  Errors json.RawMessage `json:"errors,omitempty"`
+}
+
+type Response struct {
+  *httptest.ResponseRecorder
+  t T
+}
+
+func Do(t T, h http.Handler, req *http.Request) *Response {
+  w := httptest.NewRecorder()
+  h.ServeHTTP(w, req)
+  return &Response{ResponseRecorder: w, t: t}
+}
+
+func Get(t T, h http.Handler, target string) *Response {
+  return Do(t, h, httptest.NewRequest("GET", target, nil))
+}
+
+func Post(t T, h http.Handler, target string, contentType string, body io.Reader) *Response {
+  req := httptest.NewRequest("POST", target, body)
+  req.Header.Set("Content-Type", contentType)
+  return Do(t, h, req)
+}
+
+func (r *Response) Status(code int) *Response {
+  if h, ok := r.t.(helper); ok {
+    h.Helper()
+  }
+  if r.Code != code {
+    r.t.Errorf("status: expected %d %s, got %d %s\nbody: %s",
+      code, http.StatusText(code), r.Code, http.StatusText(r.Code), r.Body.String())
+  }
+  return r
+}
+
+func (r *Response) Header(key string, value string) *Response {
+  if h, ok := r.t.(helper); ok {
+    h.Helper()
+  }
+  if got := r.HeaderMap.Get(key); got != value {
+    r.t.Errorf("header %s: expected %q, got %q", key, value, got)
+  }
+  return r
+}
+
+func (r *Response) ContentTypeJSON() *Response {
+  if h, ok := r.t.(helper); ok {
+    h.Helper()
+  }
+  if ct := r.HeaderMap.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+    r.t.Errorf("header Content-Type: expected application/json, got %q", ct)
+  }
+  return r
+}
+
+// JSON compares the body with expected, which may be a JSON string, []byte
+// or any value that marshals to JSON. Key order and whitespace are ignored.
+// Each of ignore is a dotted path such as "checks.*.latency" whose value is
+// not compared; "*" matches any object key or array index.
+func (r *Response) JSON(expected interface{}, ignore ...string) *Response {
+  if h, ok := r.t.(helper); ok {
+    h.Helper()
+  }
+  var want interface{}
+  if err := decodeExpected(expected, &want); err != nil {
+    r.t.Fatalf("invalid expected JSON: %v", err)
+    return r
+  }
+  var got interface{}
+  if err := json.Unmarshal(r.Body.Bytes(), &got); err != nil {
+    r.t.Errorf("body is not valid JSON: %v\nbody: %s", err, r.Body.String())
+    return r
+  }
+  paths := make([][]string, len(ignore))
+  for i, p := range ignore {
+    paths[i] = strings.Split(p, ".")
+  }
+  if diffs := diff(nil, want, got, paths); len(diffs) > 0 {
+    r.t.Errorf("JSON body differs:\n%s", strings.Join(diffs, "\n"))
+  }
+  return r
+}
+
+func (r *Response) Decode(v interface{}) *Response {
+  if h, ok := r.t.(helper); ok {
+    h.Helper()
+  }
+  if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+    r.t.Fatalf("could not decode body into %T: %v\nbody: %s", v, err, r.Body.String())
+  }
+  return r
+}
+
+func (r *Response) ErrorBody() *ErrorBody {
+  if h, ok := r.t.(helper); ok {
+    h.Helper()
+  }
+  body := &ErrorBody{}
+  r.Decode(body)
+  if !body.Error {
+    r.t.Errorf("expected an error body, got: %s", r.Body.String())
+  }
+  return body
+}
+
+// Error asserts that the response is a jsonhttp error with the given code
+// and message.
+func (r *Response) Error(code int, message string) *Response {
+  if h, ok := r.t.(helper); ok {
+    h.Helper()
+  }
+  r.Status(code)
+  body := r.ErrorBody()
+  if body.Message != message {
+    r.t.Errorf("error message: expected %q, got %q", message, body.Message)
+  }
+  if name := http.StatusText(code); body.Name != name {
+    r.t.Errorf("error name: expected %q, got %q", name, body.Name)
+  }
+  return r
+}
+
+func decodeExpected(expected interface{}, v interface{}) error {
+  var b []byte
+  switch e := expected.(type) {
+    case string:
+      b = []byte(e)
+    case []byte:
+      b = e
+    default:
+      var err error
+      if b, err = json.Marshal(e); err != nil {
+        return err
+      }
+  }
+  return json.Unmarshal(b, v)
+}
+
+func formatPath(path []string) string {
+  if len(path) == 0 {
+    return "$"
+  }
+  return "$." + strings.Join(path, ".")
+}
+
+func formatValue(v interface{}) string {
+  b, err := json.Marshal(v)
+  if err != nil {
+    return fmt.Sprintf("%v", v)
+  }
+  return string(b)
+}
+
+func ignored(path []string, ignore [][]string) bool {
+  for _, p := range ignore {
+    if len(p) != len(path) {
+      continue
+    }
+    match := true
+    for i := range p {
+      if p[i] != "*" && p[i] != path[i] {
+        match = false
+        break
+      }
+    }
+    if match {
+      return true
+    }
+  }
+  return false
+}
+
+func diff(path []string, want interface{}, got interface{}, ignore [][]string) []string {
+  if ignored(path, ignore) {
+    return nil
+  }
+  switch w := want.(type) {
+    case map[string]interface{}:
+      g, ok := got.(map[string]interface{})
+      if !ok {
+        break
+      }
+      var diffs []string
+      for _, k := range sortedKeys(w, g) {
+        p := append(path[:len(path):len(path)], k)
+        wv, inWant := w[k]
+        gv, inGot := g[k]
+        switch {
+          case ignored(p, ignore):
+          case !inGot:
+            diffs = append(diffs, fmt.Sprintf("%s: missing, expected %s", formatPath(p), formatValue(wv)))
+          case !inWant:
+            diffs = append(diffs, fmt.Sprintf("%s: unexpected %s", formatPath(p), formatValue(gv)))
+          default:
+            diffs = append(diffs, diff(p, wv, gv, ignore)...)
+        }
+      }
+      return diffs
+    case []interface{}:
+      g, ok := got.([]interface{})
+      if !ok {
+        break
+      }
+      if len(w) != len(g) {
+        return []string{fmt.Sprintf("%s: expected %d elements, got %d\n  expected: %s\n  got:      %s",
+          formatPath(path), len(w), len(g), formatValue(w), formatValue(g))}
+      }
+      var diffs []string
+      for i := range w {
+        diffs = append(diffs, diff(append(path[:len(path):len(path)], fmt.Sprint(i)), w[i], g[i], ignore)...)
+      }
+      return diffs
+    default:
+      if want == got {
+        return nil
+      }
+  }
+  return []string{fmt.Sprintf("%s: expected %s, got %s", formatPath(path), formatValue(want), formatValue(got))}
+}
+
+func sortedKeys(maps ...map[string]interface{}) []string {
+  seen := make(map[string]bool)
+  var keys []string
+  for _, m := range maps {
+    for k := range m {
+      if !seen[k] {
+        seen[k] = true
+        keys = append(keys, k)
+      }
+    }
+  }
+  sort.Strings(keys)
+  return keys
+}
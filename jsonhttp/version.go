@@ -0,0 +1,105 @@
+package jsonhttp
+
+import (
+  "fmt"
+  "net/http"
+  "strings"
+  "time"
+)
+
+type APIVersion struct {
+  H http.Handler
+  Deprecation time.Time
+  Sunset time.Time
+}
+
+func (v *APIVersion) deprecated() bool {
+  return !v.Deprecation.IsZero() || !v.Sunset.IsZero()
+}
+
+// VersionHandler dispatches to one of Versions. The version is taken from
+// the first path segment (/v2/...) if PathPrefix is set, then from Header,
+// then from the version parameter of an Accept header matching MediaType
+// (application/vnd.x+json;version=2), and finally from Default.
+type VersionHandler struct {
+  Versions map[string]*APIVersion
+  Default string
+  MediaType string
+  Header string
+  PathPrefix bool
+}
+
+func (h *VersionHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+  if len(h.MediaType) > 0 {
+    addVary(w.Header(), "Accept")
+  }
+  if len(h.Header) > 0 {
+    addVary(w.Header(), h.Header)
+  }
+
+  name, req := h.requestedVersion(req)
+  version, ok := h.Versions[name]
+  if !ok {
+    Error(w, fmt.Sprintf("Unknown API version: %s", name), http.StatusNotAcceptable)
+    return
+  }
+  if version.deprecated() {
+    if version.Deprecation.IsZero() {
+      w.Header().Set("Deprecation", "true")
+    } else {
+      w.Header().Set("Deprecation", version.Deprecation.UTC().Format(http.TimeFormat))
+    }
+    if !version.Sunset.IsZero() {
+      w.Header().Set("Sunset", version.Sunset.UTC().Format(http.TimeFormat))
+    }
+  }
+  version.H.ServeHTTP(w, req)
+}
+
+func (h *VersionHandler) requestedVersion(req *http.Request) (string, *http.Request) {
+  if h.PathPrefix {
+    if name, rest, ok := splitVersionPath(req.URL.Path); ok {
+      r := new(http.Request)
+      *r = *req
+      u := *req.URL
+      u.Path = rest
+      u.RawPath = ""
+      if rawName, rawRest, ok := splitVersionPath(req.URL.RawPath); ok && rawName == name {
+        u.RawPath = rawRest
+      }
+      r.URL = &u
+      return name, r
+    }
+  }
+  if len(h.Header) > 0 {
+    if name := strings.TrimSpace(req.Header.Get(h.Header)); len(name) > 0 {
+      return name, req
+    }
+  }
+  if len(h.MediaType) > 0 {
+    mediaType := strings.ToLower(h.MediaType)
+    for _, qv := range parseQualityList(req.Header.Get("Accept")) {
+      if qv.q > 0 && qv.value == mediaType {
+        if name, ok := qv.params["version"]; ok {
+          return name, req
+        }
+      }
+    }
+  }
+  return h.Default, req
+}
+
+func splitVersionPath(path string) (name string, rest string, ok bool) {
+  if !strings.HasPrefix(path, "/v") {
+    return "", path, false
+  }
+  segment := path[2:]
+  rest = "/"
+  if i := strings.Index(segment, "/"); i >= 0 {
+    segment, rest = segment[:i], segment[i:]
+  }
+  if len(segment) == 0 || segment[0] < '0' || segment[0] > '9' {
+    return "", path, false
+  }
+  return segment, rest, true
+}
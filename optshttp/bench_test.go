@@ -0,0 +1,84 @@
+package optshttp
+
+import (
+  "net/http"
+  "reflect"
+  "testing"
+  "time"
+)
+
+type benchParams struct {
+  Query    string     `form:"q,required" validate:"minlen=2"`
+  Page     int        `form:"page,default=1" validate:"min=1,max=1000"`
+  Count    *uint      `form:"count"`
+  Labels   []string   `form:"labels,csv,limit=10"`
+  Since    time.Time  `form:"since" validate:"before=Until"`
+  Until    time.Time  `form:"until"`
+  Order    string     `form:"order" validate:"oneof=asc desc"`
+  Embedded struct {
+    Draft  bool       `form:"draft"`
+    Month  time.Month `form:"month"`
+  }                   `form:",inline"`
+  ApiKey   string     `header:"X-Api-Key"`
+}
+
+func benchRequest() *http.Request {
+  url := `http://example.com/?q=gophers&count=20&labels=go,http,json&since=2017-01-01T00:00:00Z&until=2017-02-01T00:00:00Z&order=asc&draft=true&month=3`
+  req, _ := http.NewRequest("GET", url, nil)
+  req.ParseForm()
+  return req
+}
+
+func BenchmarkUnmarshalForm(b *testing.B) {
+  req := benchRequest()
+  b.ReportAllocs()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    if err := UnmarshalForm(req, &benchParams{}); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+  req := benchRequest()
+  b.ReportAllocs()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    if err := Unmarshal(req, &benchParams{}); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
+
+// BenchmarkUnmarshalFormUncached compiles the plan on every call, as
+// unmarshalling did before plans were cached.
+func BenchmarkUnmarshalFormUncached(b *testing.B) {
+  req := benchRequest()
+  t := reflect.TypeOf(benchParams{})
+  b.ReportAllocs()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    plan, err := compilePlan(t)
+    if err != nil {
+      b.Fatal(err)
+    }
+    if err := bindPlan(reflect.ValueOf(&benchParams{}).Elem(), plan, []*source{formSource(req)}, 0); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
+
+func BenchmarkMarshalForm(b *testing.B) {
+  params := &benchParams{}
+  if err := UnmarshalForm(benchRequest(), params); err != nil {
+    b.Fatal(err)
+  }
+  b.ReportAllocs()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    if _, err := MarshalForm(params); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
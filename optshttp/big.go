@@ -0,0 +1,251 @@
+package optshttp
+
+import (
+  "fmt"
+  "math"
+  "math/big"
+  "reflect"
+  "strconv"
+  "strings"
+)
+
+const(
+  flagDecimal = "decimal="
+  flagPrec = "prec="
+)
+
+// maxBigLen and maxBigExp bound the length and exponent of a big number,
+// because the time to parse one such as "1e999999" grows with them.
+const(
+  maxBigLen = 1000
+  maxBigExp = 1000
+)
+
+var bigIntType = reflect.TypeOf(big.Int{})
+var bigFloatType = reflect.TypeOf(big.Float{})
+var bigRatType = reflect.TypeOf(big.Rat{})
+
+func isBigType(t reflect.Type) bool {
+  return t == bigIntType || t == bigFloatType || t == bigRatType
+}
+
+// setBig sets a big.Int, big.Float or big.Rat. A big.Float is parsed with
+// the precision of the prec= flag, in bits, or 64. Infinities, and numbers
+// with more significant digits than the precision holds which it cannot
+// represent exactly, are errors rather than being rounded.
+func setBig(v reflect.Value, opts *tagOpts, str string) error {
+  if err := checkBigSize(opts, str); err != nil {
+    return err
+  }
+  switch x := v.Addr().Interface().(type) {
+    case *big.Int:
+      if opts.decimal {
+        n, err := parseDecimal(opts, str)
+        if err != nil {
+          return err
+        }
+        x.Set(n)
+        return nil
+      }
+      if _, ok := x.SetString(str, 10); !ok {
+        return &optsError{"integer", opts.name, str}
+      }
+    case *big.Float:
+      f, _, err := new(big.Float).SetPrec(opts.prec).Parse(str, 10)
+      if err != nil {
+        return &optsError{"number", opts.name, str}
+      }
+      if f.IsInf() {
+        return &optsError{"number", opts.name, str + " is out of range"}
+      }
+      if f.Acc() != big.Exact && significantDigits(str) > int(float64(f.Prec()) * math.Log10(2)) {
+        return &optsError{fmt.Sprintf("number with at most %d bits of precision", f.Prec()), opts.name, str}
+      }
+      x.SetPrec(f.Prec()).Set(f)
+    case *big.Rat:
+      if _, ok := x.SetString(str); !ok {
+        return &optsError{"number", opts.name, str}
+      }
+  }
+  return nil
+}
+
+// checkBigSize checks the length and exponent of a big number before it is
+// parsed.
+func checkBigSize(opts *tagOpts, str string) error {
+  if len(str) > maxBigLen {
+    return &optsError{fmt.Sprintf("number of at most %d characters", maxBigLen), opts.name, fmt.Sprintf("%d characters", len(str))}
+  }
+  mantissa := strings.ToLower(strings.TrimLeft(str, "+-"))
+  exps := "ep"
+  if strings.HasPrefix(mantissa, "0x") {
+    exps = "p"
+  }
+  i := strings.IndexAny(mantissa, exps)
+  if i < 0 {
+    return nil
+  }
+  exp, err := strconv.Atoi(strings.Replace(mantissa[i + 1:], "_", "", -1))
+  if err != nil || exp > maxBigExp || exp < -maxBigExp {
+    return &optsError{fmt.Sprintf("number with an exponent of at most %d", maxBigExp), opts.name, str}
+  }
+  return nil
+}
+
+// parseDecimal parses an amount such as "-12.34" with the decimal=N flag,
+// returning it in units of 10^-N, such as -1234 for decimal=2. More than N
+// decimal places is an error, rather than being rounded.
+func parseDecimal(opts *tagOpts, str string) (*big.Int, error) {
+  s := str
+  sign := ""
+  if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+    sign, s = s[:1], s[1:]
+  }
+  whole, frac := s, ""
+  if i := strings.Index(s, "."); i >= 0 {
+    whole, frac = s[:i], s[i + 1:]
+  }
+  if len(whole) + len(frac) == 0 || !isDigits(whole) || !isDigits(frac) {
+    return nil, &optsError{"amount", opts.name, str}
+  }
+  if len(frac) > opts.places {
+    return nil, &optsError{fmt.Sprintf("amount with at most %d decimal places", opts.places), opts.name, str}
+  }
+  n, _ := new(big.Int).SetString(sign + whole + frac + strings.Repeat("0", opts.places - len(frac)), 10)
+  return n, nil
+}
+
+// significantDigits counts the digits of the mantissa of a number such as
+// "-0.001230e5", other than leading and trailing zeros.
+func significantDigits(str string) int {
+  if i := strings.IndexAny(str, "eE"); i >= 0 {
+    str = str[:i]
+  }
+  digits := strings.Map(func(c rune) rune {
+    if c < '0' || c > '9' {
+      return -1
+    }
+    return c
+  }, str)
+  return len(strings.Trim(digits, "0"))
+}
+
+func isDigits(s string) bool {
+  for _, c := range s {
+    if c < '0' || c > '9' {
+      return false
+    }
+  }
+  return true
+}
+
+// setDecimalInt sets an integer field from an amount with the decimal=N
+// flag, checking that it fits.
+func setDecimalInt(v reflect.Value, opts *tagOpts, str string) error {
+  if err := checkBigSize(opts, str); err != nil {
+    return err
+  }
+  n, err := parseDecimal(opts, str)
+  if err != nil {
+    return err
+  }
+  outOfRange := &optsError{"amount", opts.name, str + " is out of range"}
+  switch v.Kind() {
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      if n.Sign() < 0 || !n.IsUint64() || v.OverflowUint(n.Uint64()) {
+        return outOfRange
+      }
+      v.SetUint(n.Uint64())
+    default:
+      if !n.IsInt64() || v.OverflowInt(n.Int64()) {
+        return outOfRange
+      }
+      v.SetInt(n.Int64())
+  }
+  return nil
+}
+
+// formatDecimal is the inverse of parseDecimal.
+func formatDecimal(n *big.Int, places int) string {
+  digits := new(big.Int).Abs(n).String()
+  if len(digits) <= places {
+    digits = strings.Repeat("0", places - len(digits) + 1) + digits
+  }
+  sign := ""
+  if n.Sign() < 0 {
+    sign = "-"
+  }
+  if places == 0 {
+    return sign + digits
+  }
+  return sign + digits[:len(digits) - places] + "." + digits[len(digits) - places:]
+}
+
+// formatDecimalValue formats an integer or big.Int field with the
+// decimal=N flag.
+func formatDecimalValue(v reflect.Value, opts *tagOpts) (string, bool) {
+  var n *big.Int
+  switch v.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+      n = big.NewInt(v.Int())
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      n = new(big.Int).SetUint64(v.Uint())
+    case reflect.Struct:
+      if v.Type() != bigIntType || !v.CanAddr() {
+        return "", false
+      }
+      n = v.Addr().Interface().(*big.Int)
+    default:
+      return "", false
+  }
+  return formatDecimal(n, opts.places), true
+}
+
+// bigRat converts a big number to a big.Rat, for comparison with bounds.
+func bigRat(v reflect.Value) (*big.Rat, bool) {
+  if !v.CanAddr() {
+    return nil, false
+  }
+  switch x := v.Addr().Interface().(type) {
+    case *big.Int:
+      return new(big.Rat).SetInt(x), true
+    case *big.Float:
+      r, _ := x.Rat(nil)
+      return r, r != nil
+    case *big.Rat:
+      return x, true
+  }
+  return nil, false
+}
+
+// checkBigTag checks that the decimal= flag is only given for integer and
+// big.Int fields, and the prec= flag only for big.Float fields.
+func checkBigTag(t reflect.Type, name string, opts *tagOpts) error {
+  for {
+    switch t.Kind() {
+      case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+        t = t.Elem()
+        continue
+    }
+    if !isOptional(t) {
+      break
+    }
+    value, _ := t.FieldByName("Value")
+    t = value.Type
+  }
+  if opts.decimal && t != bigIntType {
+    switch t.Kind() {
+      case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+          reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        if t == durationType || t == monthType {
+          return fmt.Errorf("Field %s has decimal flag but is a %s", name, t)
+        }
+      default:
+        return fmt.Errorf("Field %s has decimal flag but is not an integer", name)
+    }
+  }
+  if opts.prec > 0 && t != bigFloatType {
+    return fmt.Errorf("Field %s has prec flag but is not a big.Float", name)
+  }
+  return nil
+}
@@ -0,0 +1,55 @@
+//go:build go1.18
+// +build go1.18
+
+package optshttp
+
+import (
+  "context"
+  "log"
+  "net/http"
+  "github.com/istreeter/gotools/jsonhttp"
+)
+
+type paramsKey[T any] struct{}
+
+type binder[T any] struct{
+  h http.Handler
+}
+
+// BindError is called by Bind when unmarshalling fails with an error other
+// than a ValidationError, such as a bad tag on T, which is a bug rather than
+// a bad request. By default it logs err and responds with
+// jsonhttp.DefaultErrorHandler.
+var BindError = func(w http.ResponseWriter, req *http.Request, err error) {
+  log.Printf("Cannot bind parameters of %s: %v", req.URL.Path, err)
+  jsonhttp.DefaultErrorHandler.ServeHTTP(w, req)
+}
+
+// Bind returns a handler which unmarshals a T with Unmarshal before calling
+// h, and responds with a jsonhttp 400 error listing the invalid parameters
+// if that fails. h gets the T with ParamsFrom. Because the T is stored in
+// the request context, it is available to handlers run by
+// synchttp.Handlers too.
+func Bind[T any](h http.Handler) http.Handler {
+  return &binder[T]{h}
+}
+
+func (b *binder[T]) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+  params := new(T)
+  if err := Unmarshal(req, params); err != nil {
+    if _, ok := err.(*ValidationError); ok {
+      jsonhttp.ErrorFrom(w, err, http.StatusBadRequest)
+    } else {
+      BindError(w, req, err)
+    }
+    return
+  }
+  ctx := context.WithValue(req.Context(), paramsKey[T]{}, params)
+  b.h.ServeHTTP(w, req.WithContext(ctx))
+}
+
+// ParamsFrom returns the T bound by Bind[T], or nil if there is none.
+func ParamsFrom[T any](ctx context.Context) *T {
+  params, _ := ctx.Value(paramsKey[T]{}).(*T)
+  return params
+}
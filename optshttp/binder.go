@@ -0,0 +1,234 @@
+package optshttp
+
+import (
+  "net/http"
+  "reflect"
+  "strconv"
+  "sync"
+  "time"
+)
+
+// FormUnmarshaler is implemented by types with an UnmarshalForm method
+// generated by optshttpgen, which UnmarshalForm calls instead of binding
+// by reflection. PathUnmarshaler and HeaderUnmarshaler are the same for
+// UnmarshalPath and UnmarshalHeader.
+type FormUnmarshaler interface{
+  UnmarshalForm(req *http.Request) error
+}
+
+type PathUnmarshaler interface{
+  UnmarshalPath(req *http.Request) error
+}
+
+type HeaderUnmarshaler interface{
+  UnmarshalHeader(req *http.Request) error
+}
+
+type parserUse struct{
+  version int
+  uses bool
+}
+
+// parserUses caches whether a registered parser applies to a field of a
+// type, for the version of the parser registry.
+var parserUses sync.Map
+
+// useGenerated reports whether the generated methods of v bind it as the
+// reflective binding would. They do not for a nil pointer, on which they
+// would panic, nor while a registered parser applies to a field of v.
+func useGenerated(v interface{}) bool {
+  rv := reflect.ValueOf(v)
+  if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+    return false
+  }
+  parsers.RLock()
+  version, n := parsers.version, len(parsers.m)
+  parsers.RUnlock()
+  if n == 0 {
+    return true
+  }
+  t := rv.Elem().Type()
+  if u, ok := parserUses.Load(t); ok && u.(parserUse).version == version {
+    return !u.(parserUse).uses
+  }
+  plan, err := cachedPlan(t)
+  if err != nil {
+    return false
+  }
+  uses := false
+  for _, f := range plan.fields {
+    ft := t.FieldByIndex(f.index).Type
+    for !uses {
+      if _, uses = lookupParser(ft); uses {
+        break
+      }
+      if k := ft.Kind(); k != reflect.Ptr && k != reflect.Slice && k != reflect.Array {
+        break
+      }
+      ft = ft.Elem()
+    }
+  }
+  parserUses.Store(t, parserUse{version, uses})
+  return !uses
+}
+
+// Tag is a parsed field tag, for generated code.
+type Tag struct{
+  tagKey string
+  key string
+  opts *tagOpts
+}
+
+// MustTag parses the tag of a form, path or header field, and panics if the
+// tag is invalid. Generated code parses each tag once, into a package
+// variable.
+func MustTag(tagKey string, tagStr string) *Tag {
+  opts, err := parseTag(tagStr)
+  if err != nil {
+    panic(err)
+  }
+  key := opts.name
+  if tagKey == flagHeader {
+    key = http.CanonicalHeaderKey(key)
+    opts.trim = true
+  }
+  return &Tag{tagKey, key, opts}
+}
+
+// Binder binds the values of one source of a request for generated code,
+// collecting invalid and missing parameters as UnmarshalForm does.
+type Binder struct{
+  src *source
+  verr ValidationError
+}
+
+// NewBinder returns a Binder for the form, path or header values of req.
+func NewBinder(req *http.Request, tagKey string) *Binder {
+  var src *source
+  switch tagKey {
+    case flagForm:
+      src = formSource(req)
+    case flagPath:
+      src = pathSource(req, DefaultPathLookup)
+    default:
+      src = headerSource(req)
+  }
+  return &Binder{src: src}
+}
+
+func (b *Binder) add(t *Tag, err error) {
+  b.verr.add(t.tagKey, t.opts.name, err)
+}
+
+// values returns the values of t's key, or its default, as lookupField
+// does for a single source.
+func (b *Binder) values(t *Tag) ([]string, bool) {
+  vals := b.src.lookup(t.key)
+  if hasValue(vals) || (t.opts.empty && len(vals) > 0) {
+    return vals, true
+  }
+  if t.opts.def != nil {
+    return []string{*t.opts.def}, true
+  }
+  if t.opts.required {
+    b.add(t, &missingError{t.tagKey, t.opts.name})
+  }
+  return nil, false
+}
+
+// Value returns the value to bind to a scalar field, as setField does.
+func (b *Binder) Value(t *Tag) (string, bool) {
+  vals, ok := b.values(t)
+  if !ok {
+    return "", false
+  }
+  return firstValue(vals, t.opts.empty)
+}
+
+// List returns the values to bind to a slice field, split and limited as
+// setField does.
+func (b *Binder) List(t *Tag) ([]string, bool) {
+  vals, ok := b.values(t)
+  if !ok {
+    return nil, false
+  }
+  elems, err := splitList(vals, t.opts, t.opts.limit)
+  if err != nil {
+    b.add(t, err)
+    return nil, false
+  }
+  if len(elems) == 0 {
+    return nil, false
+  }
+  return elems, true
+}
+
+func (b *Binder) Int(t *Tag, str string, bits int) (int64, bool) {
+  val, err := parseInt(t.opts, str, intBits(bits))
+  return val, b.check(t, err)
+}
+
+func (b *Binder) Uint(t *Tag, str string, bits int) (uint64, bool) {
+  val, err := parseUint(t.opts, str, intBits(bits))
+  return val, b.check(t, err)
+}
+
+func (b *Binder) Float(t *Tag, str string, bits int) (float64, bool) {
+  val, err := parseFloat(t.opts, str, bits)
+  return val, b.check(t, err)
+}
+
+func (b *Binder) Bool(t *Tag, str string) (bool, bool) {
+  val, err := parseBool(t.opts, str)
+  return val, b.check(t, err)
+}
+
+func (b *Binder) Duration(t *Tag, str string) (time.Duration, bool) {
+  val, err := parseDuration(t.opts, str)
+  return val, b.check(t, err)
+}
+
+func (b *Binder) Month(t *Tag, str string) (time.Month, bool) {
+  val, err := parseMonth(t.opts, str)
+  return val, b.check(t, err)
+}
+
+// Time parses a time with the layout and time zone flags of t, including a
+// tzparam= parameter of the request.
+func (b *Binder) Time(t *Tag, str string) (time.Time, bool) {
+  opts := t.opts
+  if len(opts.tzParam) > 0 {
+    var err error
+    if opts, err = withRequestLocation(opts, b.src); err != nil {
+      b.verr.add(t.tagKey, opts.tzParam, err)
+      return time.Time{}, false
+    }
+  }
+  val, err := parseTime(opts, str)
+  return val, b.check(t, err)
+}
+
+func (b *Binder) check(t *Tag, err error) bool {
+  if err != nil {
+    b.add(t, err)
+    return false
+  }
+  return true
+}
+
+// Err returns a ValidationError of the parameters which could not be bound,
+// or nil.
+func (b *Binder) Err() error {
+  if len(b.verr.Fields) == 0 {
+    return nil
+  }
+  return &b.verr
+}
+
+// intBits treats 0 as the size of int.
+func intBits(bits int) int {
+  if bits == 0 {
+    return strconv.IntSize
+  }
+  return bits
+}
@@ -0,0 +1,156 @@
+package optshttp
+
+import (
+  "flag"
+  "fmt"
+  "os"
+  "reflect"
+  "strings"
+)
+
+const(
+  flagEnv = "env"
+  flagFlag = "flag"
+  flagUsage = "usage"
+)
+
+// UnmarshalEnv binds fields tagged env from environment variables. Fields of
+// a struct with the prefix flag are bound from variables named with the
+// struct's name and an underscore, such as MONGO_URL for env:"URL" in a
+// struct tagged env:"MONGO,prefix".
+func UnmarshalEnv(v interface{}) error {
+  return unmarshalStruct(v, []*source{{tagKey: flagEnv}})
+}
+
+// UnmarshalFlags binds fields tagged flag from the flags registered by
+// RegisterFlags, once fs has been parsed.
+func UnmarshalFlags(fs *flag.FlagSet, v interface{}) error {
+  return unmarshalStruct(v, []*source{{tagKey: flagFlag, flags: fs}})
+}
+
+// UnmarshalConfig binds flag and env tags in a single pass. A field with
+// both tags takes its value from the flag if it was given.
+func UnmarshalConfig(fs *flag.FlagSet, v interface{}) error {
+  return unmarshalStruct(v, []*source{
+    {tagKey: flagFlag, flags: fs},
+    {tagKey: flagEnv},
+  })
+}
+
+// flagValue collects the values given for a flag, leaving parsing, defaults
+// and validation to UnmarshalFlags.
+type flagValue struct{
+  vals []string
+  isBool bool
+}
+
+func (f *flagValue) String() string {
+  if f == nil {
+    return ""
+  }
+  return strings.Join(f.vals, ",")
+}
+
+func (f *flagValue) Set(val string) error {
+  f.vals = append(f.vals, val)
+  return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+  return f.isBool
+}
+
+// RegisterFlags registers a flag on fs for each field of v tagged flag,
+// with usage taken from the field's usage tag. Parse fs, then bind v with
+// UnmarshalFlags or UnmarshalConfig.
+func RegisterFlags(fs *flag.FlagSet, v interface{}) error {
+  t := reflect.TypeOf(v)
+  if t == nil {
+    return &targetError{nil, "must be a pointer to a struct"}
+  }
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+  return registerFlags(fs, t, "", 0)
+}
+
+func registerFlags(fs *flag.FlagSet, t reflect.Type, prefix string, depth int) error {
+  if depth >= MaxDepth {
+    return fmt.Errorf("Nesting of %s is deeper than %d levels", prefix, MaxDepth)
+  }
+  plan, err := cachedPlan(t)
+  if err != nil {
+    return err
+  }
+  for _, f := range plan.fields {
+    tag := f.tag(flagFlag)
+    if tag == nil {
+      continue
+    }
+    field := t.FieldByIndex(f.index)
+    name := prefix + tag.key
+    switch f.kind {
+      case kindStruct:
+        ft := field.Type
+        for ft.Kind() == reflect.Ptr {
+          ft = ft.Elem()
+        }
+        if err := registerFlags(fs, ft, name + ".", depth + 1); err != nil {
+          return err
+        }
+        continue
+      case kindFile, kindMap, kindStructSlice:
+        return fmt.Errorf("Unsupported type %s for flag %s", field.Type, name)
+    }
+    usage := field.Tag.Get(flagUsage)
+    if tag.opts.def != nil {
+      usage = fmt.Sprintf("%s (default %s)", usage, *tag.opts.def)
+    }
+    fs.Var(&flagValue{isBool: isBoolType(field.Type)}, name, usage)
+  }
+  return nil
+}
+
+func isBoolType(t reflect.Type) bool {
+  if isOptional(t) {
+    t = t.Field(0).Type
+  }
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+  return t.Kind() == reflect.Bool
+}
+
+func (s *source) lookupConfig(key string) []string {
+  switch s.tagKey {
+    case flagEnv:
+      if val, ok := os.LookupEnv(key); ok {
+        return []string{val}
+      }
+    case flagFlag:
+      if f := s.flags.Lookup(key); f != nil {
+        if fv, ok := f.Value.(*flagValue); ok {
+          return fv.vals
+        }
+        return []string{f.Value.String()}
+      }
+  }
+  return nil
+}
+
+func (s *source) configKeys() []string {
+  var keys []string
+  switch s.tagKey {
+    case flagEnv:
+      for _, kv := range os.Environ() {
+        if i := strings.Index(kv, "="); i > 0 {
+          keys = append(keys, kv[:i])
+        }
+      }
+    case flagFlag:
+      s.flags.Visit(func(f *flag.Flag) {
+        keys = append(keys, f.Name)
+      })
+  }
+  return keys
+}
@@ -0,0 +1,64 @@
+package optshttp
+
+import (
+  "fmt"
+  "strings"
+)
+
+// FieldError describes a single missing or invalid parameter.
+type FieldError struct{
+  Source string `json:"source"`
+  Key string `json:"key"`
+  Reason string `json:"reason"`
+}
+
+// ValidationError lists every missing or invalid parameter found while
+// unmarshalling, so that clients can fix them all at once. It is returned by
+// UnmarshalForm and UnmarshalPath in place of the first error.
+type ValidationError struct{
+  Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+  reasons := make([]string, len(e.Fields))
+  for i, f := range e.Fields {
+    reasons[i] = f.Reason
+  }
+  return strings.Join(reasons, "; ")
+}
+
+// Details is used by jsonhttp.ErrorFrom to render the fields.
+func (e *ValidationError) Details() interface{} {
+  return e.Fields
+}
+
+func (e *ValidationError) add(source string, key string, err error) {
+  e.Fields = append(e.Fields, &FieldError{Source: source, Key: key, Reason: err.Error()})
+}
+
+type missingError struct{
+  source string
+  optKey string
+}
+
+func (e *missingError) Error() string {
+  return fmt.Sprintf("Missing required %s parameter %s", e.source, e.optKey)
+}
+
+type unknownError struct{
+  source string
+  optKey string
+}
+
+func (e *unknownError) Error() string {
+  return fmt.Sprintf("Unknown %s parameter %s", e.source, e.optKey)
+}
+
+type repeatedError struct{
+  source string
+  optKey string
+}
+
+func (e *repeatedError) Error() string {
+  return fmt.Sprintf("Repeated %s parameter %s", e.source, e.optKey)
+}
@@ -0,0 +1,80 @@
+package optshttp_test
+
+import (
+  "github.com/istreeter/gotools/optshttp"
+  "net/http"
+  "reflect"
+  "strings"
+  "time"
+  "fmt"
+)
+
+//go:generate go run ./optshttpgen -type=genParams -output=genparams_optshttp_test.go example_gen_test.go
+
+type genLabel string
+
+type genParams struct {
+  Blog    string        `path:"blog"`
+  Page    int           `form:"page,default=1"`
+  Limit   *uint8        `form:"limit"`
+  Ids     []int64       `form:"id,csv,limit=3"`
+  Labels  []genLabel    `form:"label,required"`
+  Ratio   float32       `form:"ratio"`
+  Draft   *bool         `form:"draft"`
+  Since   time.Time     `form:"since,layout=2006-01-02,tzparam=tz"`
+  Month   time.Month    `form:"month"`
+  Timeout time.Duration `form:"timeout"`
+  Agent   string        `header:"user-agent"`
+  Embedded struct {
+    Count uint          `form:"count"`
+  }                     `form:",inline"`
+}
+
+// reflParams is genParams without the generated methods.
+type reflParams genParams
+
+func Example_generated() {
+
+  for _, url := range []string{
+    `http://example.com/?label=go&id=1,2&limit=10&draft=on&since=2017-03-01&tz=Europe/Paris&month=3&timeout=5s&count=4&ratio=0.5`,
+    `http://example.com/?id=1,2,3,4&limit=300&month=13&since=yesterday&draft=maybe`,
+  } {
+    req, _ := http.NewRequest("GET", url, nil)
+    req.Header.Set("User-Agent", "example")
+    gen, refl := &genParams{}, &reflParams{}
+    genErr := optshttp.UnmarshalForm(req, gen)
+    reflErr := optshttp.UnmarshalForm(req, refl)
+    optshttp.UnmarshalHeader(req, gen)
+    optshttp.UnmarshalHeader(req, refl)
+    fmt.Println(genErr)
+    fmt.Println(fmt.Sprint(genErr) == fmt.Sprint(reflErr), reflect.DeepEqual(gen, (*genParams)(refl)))
+  }
+
+  // Output:
+  // <nil>
+  // true true
+  // Invalid unsigned integer limit: 300; Invalid list of at most 3 values id: more than 3 values; Missing required form parameter label; Invalid boolean draft: maybe; Invalid time in format 2006-01-02 since: yesterday; Invalid date month: 13
+  // true true
+}
+
+func Example_generatedPointersAndParsers() {
+
+  req, _ := http.NewRequest("GET", "http://example.com/?label=go&limit=10", nil)
+  limit := uint8(1)
+  gen := &genParams{Limit: &limit}
+  optshttp.UnmarshalForm(req, gen)
+  fmt.Println(gen.Limit == &limit, limit, gen.Labels)
+
+  // While a parser applies to one of its fields, genParams is bound by
+  // reflection, so that the parser is used.
+  optshttp.RegisterParser(reflect.TypeOf(genLabel("")), func(s string) (interface{}, error) {
+    return genLabel(strings.ToUpper(s)), nil
+  })
+  gen = &genParams{}
+  optshttp.UnmarshalForm(req, gen)
+  fmt.Println(gen.Labels)
+
+  // Output:
+  // true 10 [go]
+  // [GO]
+}
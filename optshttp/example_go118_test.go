@@ -0,0 +1,97 @@
+//go:build go1.18
+// +build go1.18
+
+package optshttp_test
+
+import (
+  "github.com/istreeter/gotools/optshttp"
+  "github.com/istreeter/gotools/jsonhttp"
+  "github.com/gorilla/mux"
+  "net/http"
+  "net/http/httptest"
+  "time"
+  "fmt"
+)
+
+func ExampleOptional() {
+
+  type tParams struct {
+    Title   optshttp.Optional[string] `form:"title"`
+    Page    optshttp.Optional[int]    `form:"page,default=1"`
+    Draft   optshttp.Optional[bool]   `form:"draft"`
+    Summary *string                   `form:"summary,empty"`
+  }
+
+  req, _ := http.NewRequest("GET", "http://example.com/?title=&draft=false&summary=", nil)
+  params := &tParams{}
+  if err := optshttp.UnmarshalForm(req, params); err != nil {
+    panic(err)
+  }
+  fmt.Println("Title is:", params.Title.Presence, params.Title.Value == "")
+  fmt.Println("Page is:", params.Page.Presence, params.Page.Value)
+  fmt.Println("Draft is:", params.Draft.Presence, params.Draft.Value)
+  fmt.Println("Summary is:", *params.Summary == "")
+
+  vals, _ := optshttp.MarshalForm(params)
+  fmt.Println(vals.Encode())
+
+  // Output:
+  // Title is: empty true
+  // Page is: absent 1
+  // Draft is: present false
+  // Summary is: true
+  // draft=false&summary=&title=
+}
+
+func ExampleBindError() {
+
+  type tParams struct {
+    Page int `form:"page" validate:"min=abc"`
+  }
+
+  defer func(f func(http.ResponseWriter, *http.Request, error)) { optshttp.BindError = f }(optshttp.BindError)
+  optshttp.BindError = func(w http.ResponseWriter, req *http.Request, err error) {
+    fmt.Println(err)
+    jsonhttp.DefaultErrorHandler.ServeHTTP(w, req)
+  }
+
+  h := optshttp.Bind[tParams](http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+    jsonhttp.OK(w, optshttp.ParamsFrom[tParams](req.Context()))
+  }))
+
+  req, _ := http.NewRequest("GET", "http://example.com/posts?page=2", nil)
+  w := httptest.NewRecorder()
+  h.ServeHTTP(w, req)
+  fmt.Print(w.Code, " ", w.Body.String())
+
+  // Output:
+  // Invalid min "abc" for field Page: strconv.ParseInt: parsing "abc": invalid syntax
+  // 500 {"error":true,"message":"Server Error","name":"Internal Server Error"}
+}
+
+func ExampleBind() {
+
+  type tParams struct {
+    Blog string `path:"blog"`
+    Page int    `form:"page,default=1" validate:"min=1"`
+  }
+
+  h := jsonhttp.HandleWithMsgs(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+    params := optshttp.ParamsFrom[tParams](req.Context())
+    jsonhttp.OK(w, map[string]interface{}{"blog": params.Blog, "page": params.Page})
+  }), time.Second)
+
+  r := mux.NewRouter()
+  r.Handle("/blogs/{blog}/posts", optshttp.Bind[tParams](h))
+
+  for _, url := range []string{"http://example.com/blogs/my-blog/posts?page=2", "http://example.com/blogs/my-blog/posts?page=0"} {
+    req, _ := http.NewRequest("GET", url, nil)
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    fmt.Print(w.Code, " ", w.Body.String())
+  }
+
+  // Output:
+  // 200 {"blog":"my-blog","page":2}
+  // 400 {"error":true,"message":"Invalid page: must be at least 1","name":"Bad Request","errors":[{"source":"form","key":"page","reason":"Invalid page: must be at least 1"}]}
+}
@@ -0,0 +1,44 @@
+//go:build go1.22
+// +build go1.22
+
+package optshttp_test
+
+import (
+  "github.com/istreeter/gotools/optshttp"
+  "net/http"
+  "net/http/httptest"
+  "fmt"
+)
+
+func ExamplePathValue() {
+
+  type tParams struct {
+    Blog  string `path:"blog"`
+    Post  int64  `path:"post"`
+    Draft bool   `form:"draft"`
+  }
+
+  mux := http.NewServeMux()
+  mux.HandleFunc("GET /blogs/{blog}/posts/{post}", func(w http.ResponseWriter, req *http.Request) {
+    params := &tParams{}
+    if err := optshttp.UnmarshalWith(optshttp.PathValue, req, params); err != nil {
+      fmt.Println(err)
+      return
+    }
+    fmt.Println("Blog is:", params.Blog)
+    fmt.Println("Post is:", params.Post)
+    fmt.Println("Draft is:", params.Draft)
+  })
+
+  req, _ := http.NewRequest("GET", "http://example.com/blogs/my-blog/posts/10?draft=true", nil)
+  mux.ServeHTTP(httptest.NewRecorder(), req)
+
+  req, _ = http.NewRequest("GET", "http://example.com/blogs/my-blog/posts/ten", nil)
+  mux.ServeHTTP(httptest.NewRecorder(), req)
+
+  // Output:
+  // Blog is: my-blog
+  // Post is: 10
+  // Draft is: true
+  // Invalid integer post: ten
+}
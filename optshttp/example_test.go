@@ -2,11 +2,27 @@ package optshttp_test
 
 import (
   "github.com/istreeter/gotools/optshttp"
+  "github.com/istreeter/gotools/jsonhttp"
   "github.com/gorilla/mux"
+  "context"
+  "flag"
+  "os"
+  "math/big"
   "net/http"
   "net/http/httptest"
   "time"
   "fmt"
+  "errors"
+  "reflect"
+  "strconv"
+  "strings"
+  "bytes"
+  "io"
+  "io/ioutil"
+  "mime"
+  "mime/multipart"
+  "net/textproto"
+  "path/filepath"
 )
 
 func ExampleUnmarshalForm() {
@@ -97,3 +113,704 @@ func ExampleUnmarshalPath() {
   // Date is: 1985-10-26 01:21:00 +0000 UTC
   // PDate is: 1834-08-01 12:00:00 +0000 UTC
 }
+
+func ExampleUnmarshalForm_scalars() {
+
+  type tParams struct {
+    Int8    int8          `form:"int8"`
+    Int64   int64         `form:"int64"`
+    Uint16  uint16        `form:"uint16"`
+    Float   float64       `form:"float"`
+    PFloat  *float32      `form:"pfloat"`
+    Bool    bool          `form:"bool"`
+    PBool   *bool         `form:"pbool"`
+    Timeout time.Duration `form:"timeout"`
+  }
+
+  url := `http://example.com/?int8=-100&int64=9007199254740993&uint16=65535&float=2.5&pfloat=0.125&bool=on&pbool=0&timeout=1m30s`
+  req, _ := http.NewRequest("GET", url, nil)
+
+  params := &tParams{}
+  if err := optshttp.UnmarshalForm(req, params); err != nil {
+    panic(err)
+  }
+  fmt.Println(params.Int8, params.Int64, params.Uint16, params.Float, *params.PFloat, params.Bool, *params.PBool, params.Timeout)
+
+  for _, url := range []string{`http://example.com/?int8=200`, `http://example.com/?bool=yes`, `http://example.com/?timeout=90`} {
+    req, _ = http.NewRequest("GET", url, nil)
+    fmt.Println(optshttp.UnmarshalForm(req, &tParams{}))
+  }
+
+  type tBadParams struct {
+    Complex complex128 `form:"complex"`
+  }
+  req, _ = http.NewRequest("GET", `http://example.com/?complex=1`, nil)
+  fmt.Println(optshttp.UnmarshalForm(req, &tBadParams{}))
+
+  // Output:
+  // -100 9007199254740993 65535 2.5 0.125 true false 1m30s
+  // Invalid integer int8: 200
+  // Invalid boolean bool: yes
+  // Invalid duration timeout: 90
+  // Unsupported type complex128 for complex
+}
+
+func ExampleUnmarshalForm_slices() {
+
+  type tParams struct {
+    Labels []string     `form:"label"`
+    Ids    []int64      `form:"ids,csv"`
+    Months *[]time.Month `form:"months,pipe"`
+    Pair   [2]float64   `form:"pair,csv"`
+    Few    []uint       `form:"few,csv,limit=3"`
+  }
+
+  url := `http://example.com/?label=go&label=http&ids=10,20,30&months=3|6&pair=1.5,2.5`
+  req, _ := http.NewRequest("GET", url, nil)
+
+  params := &tParams{}
+  if err := optshttp.UnmarshalForm(req, params); err != nil {
+    panic(err)
+  }
+  fmt.Println("Labels are:", params.Labels)
+  fmt.Println("Ids are:", params.Ids)
+  fmt.Println("Months are:", *params.Months)
+  fmt.Println("Pair is:", params.Pair)
+
+  for _, url := range []string{`http://example.com/?few=1,2,3,4`, `http://example.com/?pair=1,2,3`, `http://example.com/?ids=10,x`} {
+    req, _ = http.NewRequest("GET", url, nil)
+    fmt.Println(optshttp.UnmarshalForm(req, &tParams{}))
+  }
+
+  // Output:
+  // Labels are: [go http]
+  // Ids are: [10 20 30]
+  // Months are: [March June]
+  // Pair is: [1.5 2.5]
+  // Invalid list of at most 3 values few: more than 3 values
+  // Invalid list of at most 2 values pair: more than 2 values
+  // Invalid integer ids: x
+}
+
+type blogID string
+
+func (id *blogID) UnmarshalText(text []byte) error {
+  if len(text) == 0 || strings.Trim(string(text), "0123456789") != "" {
+    return errors.New("blog ids are numeric")
+  }
+  *id = blogID("blog-" + string(text))
+  return nil
+}
+
+type snowflake struct {
+  ms int64
+  seq int64
+}
+
+func ExampleRegisterParser() {
+
+  optshttp.RegisterParser(reflect.TypeOf(snowflake{}), func(s string) (interface{}, error) {
+    id, err := strconv.ParseInt(s, 10, 64)
+    if err != nil {
+      return nil, err
+    }
+    return snowflake{ms: id >> 22 + 1288834974657, seq: id & 0xfff}, nil
+  })
+
+  type tParams struct {
+    Blog    blogID      `path:"blog"`
+    Tweet   *snowflake  `path:"tweet"`
+    Related []blogID    `form:"related,csv"`
+  }
+
+  handler := func(w http.ResponseWriter, req *http.Request) {
+    params := &tParams{}
+    if err := optshttp.UnmarshalPath(req, params); err != nil {
+      fmt.Fprintln(w, err)
+      return
+    }
+    if err := optshttp.UnmarshalForm(req, params); err != nil {
+      fmt.Fprintln(w, err)
+      return
+    }
+    fmt.Fprintln(w, "Blog is:", params.Blog)
+    fmt.Fprintln(w, "Tweet is:", time.Unix(0, params.Tweet.ms * int64(time.Millisecond)).UTC(), params.Tweet.seq)
+    fmt.Fprintln(w, "Related are:", params.Related)
+  }
+
+  r := mux.NewRouter()
+  r.HandleFunc("/blogs/{blog}/tweets/{tweet}", handler)
+
+  for _, url := range []string{
+    `http://example.com/blogs/123/tweets/837963210767650817?related=4,5`,
+    `http://example.com/blogs/abc/tweets/837963210767650817`,
+    `http://example.com/blogs/123/tweets/x`,
+  } {
+    req, _ := http.NewRequest("GET", url, nil)
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    fmt.Print(w.Body.String())
+  }
+
+  // Output:
+  // Blog is: blog-123
+  // Tweet is: 2017-03-04 09:49:32.707 +0000 UTC 1
+  // Related are: [blog-4 blog-5]
+  // Invalid optshttp_test.blogID blog: abc
+  // Invalid optshttp_test.snowflake tweet: x
+}
+
+func ExampleValidationError() {
+
+  type tParams struct {
+    Query  string    `form:"q,required"`
+    Page   int       `form:"page,default=1"`
+    Labels []string  `form:"labels,csv,default=go,http"`
+    Since  time.Time `form:"since"`
+    Count  uint8     `form:"count"`
+  }
+
+  handler := func(w http.ResponseWriter, req *http.Request) {
+    params := &tParams{}
+    if err := optshttp.UnmarshalForm(req, params); err != nil {
+      jsonhttp.ErrorFrom(w, err, http.StatusBadRequest)
+      return
+    }
+    jsonhttp.OK(w, params)
+  }
+
+  for _, url := range []string{
+    `http://example.com/?q=gophers`,
+    `http://example.com/?q=&q=gophers&page=&page=2`,
+    `http://example.com/?since=yesterday&count=1000`,
+  } {
+    req, _ := http.NewRequest("GET", url, nil)
+    w := httptest.NewRecorder()
+    handler(w, req)
+    fmt.Printf("%d - %s", w.Code, w.Body.String())
+  }
+
+  // Output:
+  // 200 - {"Query":"gophers","Page":1,"Labels":["go","http"],"Since":"0001-01-01T00:00:00Z","Count":0}
+  // 200 - {"Query":"gophers","Page":2,"Labels":["go","http"],"Since":"0001-01-01T00:00:00Z","Count":0}
+  // 400 - {"error":true,"message":"Missing required form parameter q; Invalid time in RFC3339 format since: yesterday; Invalid unsigned integer count: 1000","name":"Bad Request","errors":[{"source":"form","key":"q","reason":"Missing required form parameter q"},{"source":"form","key":"since","reason":"Invalid time in RFC3339 format since: yesterday"},{"source":"form","key":"count","reason":"Invalid unsigned integer count: 1000"}]}
+}
+
+func ExampleUnmarshalForm_validate() {
+
+  type tParams struct {
+    Since  time.Time  `form:"since" validate:"min=2000-01-01T00:00:00Z,before=Until"`
+    Until  time.Time  `form:"until"`
+    Count  int        `form:"count,default=20" validate:"min=1,max=100"`
+    Order  string     `form:"order" validate:"oneof=asc desc"`
+    Labels []string   `form:"labels,csv" validate:"maxlen=3,regex=^[a-z]{2,}$"`
+    Title  *string    `form:"title" validate:"minlen=3"`
+  }
+
+  for _, url := range []string{
+    `http://example.com/?since=2017-01-01T00:00:00Z&until=2017-02-01T00:00:00Z&order=asc&labels=go,http`,
+    `http://example.com/?since=2017-03-01T00:00:00Z&until=2017-02-01T00:00:00Z&count=0&order=up&labels=go,X&title=ab`,
+    `http://example.com/?since=1999-12-31T00:00:00Z&count=101&labels=a,b,c,d`,
+  } {
+    req, _ := http.NewRequest("GET", url, nil)
+    params := &tParams{}
+    if err := optshttp.UnmarshalForm(req, params); err != nil {
+      for _, f := range err.(*optshttp.ValidationError).Fields {
+        fmt.Println(f.Reason)
+      }
+      continue
+    }
+    fmt.Println("OK:", params.Count, params.Order, params.Labels)
+  }
+
+  // Output:
+  // OK: 20 asc [go http]
+  // Invalid since: must be before until
+  // Invalid count: must be at least 1
+  // Invalid order: must be one of [asc desc]
+  // Invalid labels: must match ^[a-z]{2,}$
+  // Invalid title: must have length at least 3
+  // Invalid since: must be at least 2000-01-01T00:00:00Z
+  // Invalid count: must be at most 100
+  // Invalid labels: must have length at most 3
+}
+
+func ExampleUnmarshal() {
+
+  type tParams struct {
+    Blog     string   `path:"blog"`
+    ApiKey   string   `header:"X-Api-Key,required"`
+    Etags    []string `header:"If-None-Match,csv"`
+    Session  string   `cookie:"session"`
+    Locale   string   `form:"locale" header:"Accept-Language" cookie:"locale,default=en"`
+    Page     int      `form:"page,default=1"`
+  }
+
+  handler := func(w http.ResponseWriter, req *http.Request) {
+    params := &tParams{}
+    if err := optshttp.Unmarshal(req, params); err != nil {
+      fmt.Fprintln(w, err)
+      return
+    }
+    fmt.Fprintf(w, "%+v\n", *params)
+  }
+
+  r := mux.NewRouter()
+  r.HandleFunc("/blogs/{blog}", handler)
+
+  req, _ := http.NewRequest("GET", "http://example.com/blogs/123?page=2", nil)
+  req.Header.Set("X-Api-Key", "secret")
+  req.Header.Set("If-None-Match", `"abc", "def"`)
+  req.Header.Set("Accept-Language", "fr")
+  req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+  w := httptest.NewRecorder()
+  r.ServeHTTP(w, req)
+  fmt.Print(w.Body.String())
+
+  req, _ = http.NewRequest("GET", "http://example.com/blogs/123?locale=de", nil)
+  req.Header.Set("Accept-Language", "fr")
+  w = httptest.NewRecorder()
+  r.ServeHTTP(w, req)
+  fmt.Print(w.Body.String())
+
+  req, _ = http.NewRequest("GET", "http://example.com/blogs/123", nil)
+  req.Header.Set("X-Api-Key", "secret")
+  w = httptest.NewRecorder()
+  r.ServeHTTP(w, req)
+  fmt.Print(w.Body.String())
+
+  // Output:
+  // {Blog:123 ApiKey:secret Etags:["abc" "def"] Session:s1 Locale:fr Page:2}
+  // Missing required header parameter X-Api-Key
+  // {Blog:123 ApiKey:secret Etags:[] Session: Locale:en Page:1}
+}
+
+func ExampleUnmarshalForm_files() {
+
+  type tParams struct {
+    Title   string                  `form:"title"`
+    Archive io.ReadCloser           `form:"archive,required,maxsize=1KB,accept=application/zip|application/json"`
+    Images  []*multipart.FileHeader `form:"images,limit=2,accept=image/*"`
+  }
+
+  handler := func(w http.ResponseWriter, req *http.Request) {
+    params := &tParams{}
+    if err := optshttp.UnmarshalForm(req, params); err != nil {
+      fmt.Fprintln(w, err)
+      return
+    }
+    archive, _ := ioutil.ReadAll(params.Archive)
+    fmt.Fprintf(w, "%s: %s with %d images\n", params.Title, archive, len(params.Images))
+  }
+
+  upload := func(files map[string][]string) *http.Request {
+    body := &bytes.Buffer{}
+    mw := multipart.NewWriter(body)
+    mw.WriteField("title", "Export")
+    for field, names := range files {
+      for _, name := range names {
+        h := make(textproto.MIMEHeader)
+        h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, field, name))
+        h.Set("Content-Type", mime.TypeByExtension(filepath.Ext(name)))
+        fw, _ := mw.CreatePart(h)
+        fw.Write([]byte(`{"posts":[]}`))
+      }
+    }
+    mw.Close()
+    req, _ := http.NewRequest("POST", "http://example.com/upload", body)
+    req.Header.Set("Content-Type", mw.FormDataContentType())
+    return req
+  }
+
+  for _, files := range []map[string][]string{
+    {"archive": {"blog.json"}, "images": {"a.png", "b.jpg"}},
+    {"images": {"a.png", "b.txt"}},
+    {"archive": {"blog.txt"}, "images": {"a.png", "b.png", "c.png"}},
+  } {
+    // A server cancels the context of each request when its handler returns,
+    // which closes the archive and removes temporary files.
+    ctx, cancel := context.WithCancel(context.Background())
+    w := httptest.NewRecorder()
+    handler(w, upload(files).WithContext(ctx))
+    cancel()
+    fmt.Print(w.Body.String())
+  }
+
+  // Output:
+  // Export: {"posts":[]} with 2 images
+  // Missing required form parameter archive; Invalid file type images: text/plain
+  // Invalid file type archive: text/plain; Invalid list of at most 2 files images: 3 files
+}
+
+func ExampleMarshalForm() {
+
+  type tParams struct {
+    Blog   string     `path:"blog"`
+    Post   int64      `path:"post"`
+    Since  time.Time  `form:"since"`
+    Until  *time.Time `form:"until"`
+    Labels []string   `form:"labels,csv"`
+    Ids    []int      `form:"id"`
+    Page   int        `form:"page,default=1"`
+    Draft  *bool      `form:"draft"`
+    Embedded struct {
+      Count uint      `form:"count"`
+    }                 `form:",inline"`
+  }
+
+  draft := false
+  params := &tParams{
+    Blog: "my blog",
+    Post: 10,
+    Since: time.Date(2017, time.March, 1, 12, 0, 0, 0, time.UTC),
+    Labels: []string{"go", "http"},
+    Ids: []int{4, 5},
+    Draft: &draft,
+  }
+  params.Embedded.Count = 20
+
+  vals, err := optshttp.MarshalForm(params)
+  if err != nil {
+    panic(err)
+  }
+  fmt.Println(vals.Encode())
+
+  u, err := optshttp.TemplateURL("/blogs/{blog}/posts/{post:[0-9]+}", params)
+  if err != nil {
+    panic(err)
+  }
+  fmt.Println(u)
+
+  r := mux.NewRouter()
+  r.HandleFunc("/blogs/{blog}/posts/{post:[0-9]+}", nil).Name("post")
+  u, err = optshttp.RouteURL(r, "post", params)
+  if err != nil {
+    panic(err)
+  }
+  fmt.Println(u)
+
+  req, _ := http.NewRequest("GET", "http://example.com" + u.String(), nil)
+  var match mux.RouteMatch
+  r.Match(req, &match)
+  req = mux.SetURLVars(req, match.Vars)
+  roundTrip := &tParams{}
+  if err := optshttp.Unmarshal(req, roundTrip); err != nil {
+    panic(err)
+  }
+  fmt.Println(reflect.DeepEqual(params, roundTrip))
+
+  params.Post = 0
+  u, err = optshttp.TemplateURL("/blogs/{blog}/posts/{post:[0-9]+}", params)
+  if err != nil {
+    panic(err)
+  }
+  fmt.Println(u.Path)
+  u, err = optshttp.RouteURL(r, "post", params)
+  if err != nil {
+    panic(err)
+  }
+  fmt.Println(u.Path)
+
+  params.Labels = []string{"go,http"}
+  _, err = optshttp.MarshalForm(params)
+  fmt.Println(err)
+
+  // Output:
+  // count=20&draft=false&id=4&id=5&labels=go%2Chttp&page=0&since=2017-03-01T12%3A00%3A00Z
+  // /blogs/my%20blog/posts/10?count=20&draft=false&id=4&id=5&labels=go%2Chttp&page=0&since=2017-03-01T12%3A00%3A00Z
+  // /blogs/my%20blog/posts/10?count=20&draft=false&id=4&id=5&labels=go%2Chttp&page=0&since=2017-03-01T12%3A00%3A00Z
+  // true
+  // /blogs/my blog/posts/0
+  // /blogs/my blog/posts/0
+  // Element "go,http" of labels contains the separator ","
+}
+
+func ExampleUnmarshalForm_timeFormats() {
+
+  type tParams struct {
+    Since   time.Time   `form:"since,layout=2006-01-02"`
+    Until   *time.Time  `form:"until,layout=2006-01-02 15:04,tzparam=tz"`
+    Updated time.Time   `form:"updated,unix"`
+    Seen    []time.Time `form:"seen,csv,unixms,tz=America/New_York"`
+  }
+
+  url := `http://example.com/?since=2017-03-01&until=2017-03-02+09:30&tz=Europe/Paris&updated=1488326400&seen=1488326400000,1488326400500`
+  req, _ := http.NewRequest("GET", url, nil)
+
+  params := &tParams{}
+  if err := optshttp.UnmarshalForm(req, params); err != nil {
+    panic(err)
+  }
+  fmt.Println("Since is:", params.Since)
+  fmt.Println("Until is:", *params.Until)
+  fmt.Println("Updated is:", params.Updated)
+  fmt.Println("Seen is:", params.Seen)
+
+  vals, _ := optshttp.MarshalForm(params)
+  fmt.Println(vals.Encode())
+
+  req, _ = http.NewRequest("GET", `http://example.com/?since=01/03/2017&until=2017-03-02+09:30&tz=Mars/Olympus&updated=yesterday`, nil)
+  fmt.Println(optshttp.UnmarshalForm(req, &tParams{}))
+
+  // Output:
+  // Since is: 2017-03-01 00:00:00 +0000 UTC
+  // Until is: 2017-03-02 09:30:00 +0100 CET
+  // Updated is: 2017-03-01 00:00:00 +0000 UTC
+  // Seen is: [2017-02-28 19:00:00 -0500 EST 2017-02-28 19:00:00.5 -0500 EST]
+  // seen=1488326400000%2C1488326400500&since=2017-03-01&until=2017-03-02+08%3A30&updated=1488326400
+  // Invalid time in format 2006-01-02 since: 01/03/2017; Invalid time zone tz: Mars/Olympus; Invalid Unix time in seconds updated: yesterday
+}
+
+func ExampleUnmarshalForm_nested() {
+
+  type tAuthor struct {
+    Name  string `form:"name,required"`
+    Email string `form:"email"`
+  }
+
+  type tItem struct {
+    Id    int    `form:"id"`
+    Title string `form:"title"`
+  }
+
+  type tParams struct {
+    Author  tAuthor           `form:"author,prefix"`
+    Editor  *tAuthor          `form:"editor,prefix"`
+    Filter  map[string]string `form:"filter"`
+    Counts  map[string][]int  `form:"count,csv"`
+    Items   []tItem           `form:"items,limit=10"`
+  }
+
+  url := `http://example.com/?author.name=Ian&author.email=ian@example.com&filter[label]=go&filter[status]=draft&count[a]=1,2&items[0].id=4&items[0].title=First&items[1].id=5`
+  req, _ := http.NewRequest("GET", url, nil)
+
+  params := &tParams{}
+  if err := optshttp.UnmarshalForm(req, params); err != nil {
+    panic(err)
+  }
+  fmt.Println("Author is:", params.Author)
+  fmt.Println("Editor is:", params.Editor)
+  fmt.Println("Filter is:", params.Filter)
+  fmt.Println("Counts are:", params.Counts)
+  fmt.Println("Items are:", params.Items)
+
+  vals, _ := optshttp.MarshalForm(params)
+  fmt.Println(vals.Encode())
+
+  req, _ = http.NewRequest("GET", `http://example.com/?editor.email=ed@example.com&count[b]=x&items[10].id=1`, nil)
+  fmt.Println(optshttp.UnmarshalForm(req, &tParams{}))
+
+  // Output:
+  // Author is: {Ian ian@example.com}
+  // Editor is: <nil>
+  // Filter is: map[label:go status:draft]
+  // Counts are: map[a:[1 2]]
+  // Items are: [{4 First} {5 }]
+  // author.email=ian%40example.com&author.name=Ian&count%5Ba%5D=1%2C2&filter%5Blabel%5D=go&filter%5Bstatus%5D=draft&items%5B0%5D.id=4&items%5B0%5D.title=First&items%5B1%5D.id=5
+  // Missing required form parameter author.name; Missing required form parameter editor.name; Invalid integer count[b]: x; Invalid list of at most 10 values items: index 10
+}
+
+func ExamplePathLookupFunc() {
+
+  type paramsKey struct{}
+
+  // A router which puts its params in the request context.
+  lookup := optshttp.PathLookupFunc(func(req *http.Request, name string) (string, bool) {
+    params, _ := req.Context().Value(paramsKey{}).(map[string]string)
+    val, ok := params[name]
+    return val, ok
+  })
+
+  type tParams struct {
+    Blog string `path:"blog"`
+    Post int64  `path:"post"`
+  }
+
+  req, _ := http.NewRequest("GET", "http://example.com/blogs/my-blog/posts/10", nil)
+  req = req.WithContext(context.WithValue(req.Context(), paramsKey{}, map[string]string{"blog": "my-blog", "post": "10"}))
+
+  params := &tParams{}
+  if err := optshttp.UnmarshalPathWith(lookup, req, params); err != nil {
+    panic(err)
+  }
+  fmt.Println("Blog is:", params.Blog)
+  fmt.Println("Post is:", params.Post)
+
+  // Output:
+  // Blog is: my-blog
+  // Post is: 10
+}
+
+func ExampleUnmarshalFormStrict() {
+
+  type tParams struct {
+    Since  time.Time         `form:"since,layout=2006-01-02"`
+    Labels []string          `form:"label"`
+    Page   int               `form:"page"`
+    Filter map[string]string `form:"filter"`
+    Embedded struct {
+      Count uint             `form:"count"`
+    }                        `form:",inline"`
+  }
+
+  url := `http://example.com/?since=2017-03-01&label=go&label=http&count=20&filter[status]=draft`
+  req, _ := http.NewRequest("GET", url, nil)
+  params := &tParams{}
+  fmt.Println("Error is:", optshttp.UnmarshalFormStrict(req, params))
+  fmt.Println("Labels are:", params.Labels)
+
+  url = `http://example.com/?sinec=2017-03-01&page=1&page=2&filter[status]=draft&filter[status]=live`
+  req, _ = http.NewRequest("GET", url, nil)
+  err := optshttp.UnmarshalFormStrict(req, &tParams{})
+  for _, f := range err.(*optshttp.ValidationError).Fields {
+    fmt.Println(f.Key, "-", f.Reason)
+  }
+
+  // Output:
+  // Error is: <nil>
+  // Labels are: [go http]
+  // page - Repeated form parameter page
+  // filter[status] - Repeated form parameter filter[status]
+  // sinec - Unknown form parameter sinec
+}
+
+func ExampleRegisterFlags() {
+
+  type tMongo struct {
+    URL      string        `env:"URL,required" flag:"url" usage:"MongoDB URL"`
+    Timeout  time.Duration `env:"TIMEOUT" flag:"timeout,default=10s"`
+  }
+
+  type tConfig struct {
+    Mongo    tMongo        `env:"MONGO,prefix" flag:"mongo,prefix"`
+    Blogs    []string      `env:"BLOGS,csv" flag:"blog"`
+    Interval time.Duration `flag:"interval,default=1m" validate:"min=1s"`
+    Verbose  bool          `flag:"v" usage:"Log every sync"`
+  }
+
+  os.Setenv("MONGO_URL", "mongodb://localhost/blogs")
+  os.Setenv("MONGO_TIMEOUT", "5s")
+  os.Setenv("BLOGS", "one,two")
+  defer os.Unsetenv("MONGO_URL")
+  defer os.Unsetenv("MONGO_TIMEOUT")
+  defer os.Unsetenv("BLOGS")
+
+  fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+  if err := optshttp.RegisterFlags(fs, &tConfig{}); err != nil {
+    panic(err)
+  }
+  fs.Parse([]string{"-v", "-mongo.timeout", "30s", "-blog", "three"})
+
+  config := &tConfig{}
+  if err := optshttp.UnmarshalConfig(fs, config); err != nil {
+    panic(err)
+  }
+  fmt.Println("Mongo is:", config.Mongo)
+  fmt.Println("Blogs are:", config.Blogs)
+  fmt.Println("Interval is:", config.Interval)
+  fmt.Println("Verbose is:", config.Verbose)
+
+  fs = flag.NewFlagSet("sync", flag.ContinueOnError)
+  optshttp.RegisterFlags(fs, &tConfig{})
+  fs.Parse([]string{"-interval", "10ms"})
+  os.Unsetenv("MONGO_URL")
+  fmt.Println(optshttp.UnmarshalConfig(fs, &tConfig{}))
+
+  // Output:
+  // Mongo is: {mongodb://localhost/blogs 30s}
+  // Blogs are: [three]
+  // Interval is: 1m0s
+  // Verbose is: true
+  // Missing required env parameter MONGO_URL; Invalid interval: must be at least 1s
+}
+
+func ExampleFilterSpec() {
+
+  type tParams struct {
+    Sort   optshttp.SortSpec   `form:"sort,fields=published|updated|title"`
+    Filter optshttp.FilterSpec `form:"filter,fields=labels|published"`
+  }
+
+  url := `http://example.com/?sort=-published,title&filter=labels:go,published>=2017-01-01,labels!:draft`
+  req, _ := http.NewRequest("GET", url, nil)
+  params := &tParams{}
+  if err := optshttp.UnmarshalForm(req, params); err != nil {
+    panic(err)
+  }
+  for _, f := range params.Sort {
+    fmt.Println("Sort by:", f.Field, f.Desc)
+  }
+  for _, t := range params.Filter {
+    fmt.Printf("Filter: %s %s %s\n", t.Field, t.Op, t.Value)
+  }
+
+  vals, _ := optshttp.MarshalForm(params)
+  fmt.Println(vals.Encode())
+
+  req, _ = http.NewRequest("GET", `http://example.com/?sort=-author&filter=published~2017`, nil)
+  fmt.Println(optshttp.UnmarshalForm(req, &tParams{}))
+
+  // Output:
+  // Sort by: published true
+  // Sort by: title false
+  // Filter: labels : go
+  // Filter: published >= 2017-01-01
+  // Filter: labels !: draft
+  // filter=labels%3Ago%2Cpublished%3E%3D2017-01-01%2Clabels%21%3Adraft&sort=-published%2Ctitle
+  // Invalid sort field sort: author; Invalid filter filter: published~2017
+}
+
+func ExampleUnmarshalForm_bigNumbers() {
+
+  type tParams struct {
+    Id     *big.Int   `form:"id"`
+    Ratio  *big.Rat   `form:"ratio"`
+    Total  *big.Float `form:"total,prec=128" validate:"min=0"`
+    Price  int64      `form:"price,decimal=2"`
+    Refund *big.Int   `form:"refund,decimal=4"`
+    Fee    uint16     `form:"fee,decimal=2"`
+  }
+
+  url := `http://example.com/?id=123456789012345678901234567890&ratio=1/3&total=1e40&price=-12.5&refund=0.0001&fee=655.35`
+  req, _ := http.NewRequest("GET", url, nil)
+  params := &tParams{}
+  if err := optshttp.UnmarshalForm(req, params); err != nil {
+    panic(err)
+  }
+  fmt.Println("Id is:", params.Id)
+  fmt.Println("Ratio is:", params.Ratio)
+  fmt.Println("Total is:", params.Total.Text('g', 40))
+  fmt.Println("Price is:", params.Price)
+  fmt.Println("Refund is:", params.Refund)
+  fmt.Println("Fee is:", params.Fee)
+
+  vals, _ := optshttp.MarshalForm(params)
+  fmt.Println(vals.Encode())
+
+  url = `http://example.com/?id=12.5&ratio=1/0&total=-1&price=1.234&refund=1e3&fee=655.36`
+  req, _ = http.NewRequest("GET", url, nil)
+  fmt.Println(optshttp.UnmarshalForm(req, &tParams{}))
+
+  for _, total := range []string{"Inf", "1e1000000000", "1234567890123456789012345678901234567890.5"} {
+    req, _ = http.NewRequest("GET", "http://example.com/?total=" + total, nil)
+    fmt.Println(optshttp.UnmarshalForm(req, &tParams{}))
+  }
+
+  url = "http://example.com/?ratio=1e999999&id=" + strings.Repeat("9", 2000)
+  req, _ = http.NewRequest("GET", url, nil)
+  fmt.Println(optshttp.UnmarshalForm(req, &tParams{}))
+
+  // Output:
+  // Id is: 123456789012345678901234567890
+  // Ratio is: 1/3
+  // Total is: 1e+40
+  // Price is: -1250
+  // Refund is: 1
+  // Fee is: 65535
+  // fee=655.35&id=123456789012345678901234567890&price=-12.50&ratio=1%2F3&refund=0.0001&total=1e%2B40
+  // Invalid integer id: 12.5; Invalid number ratio: 1/0; Invalid amount with at most 2 decimal places price: 1.234; Invalid amount refund: 1e3; Invalid amount fee: 655.36 is out of range; Invalid total: must be at least 0
+  // Invalid number total: Inf is out of range
+  // Invalid number with an exponent of at most 1000 total: 1e1000000000
+  // Invalid number with at most 128 bits of precision total: 1234567890123456789012345678901234567890.5
+  // Invalid number of at most 1000 characters id: 2000 characters; Invalid number with an exponent of at most 1000 ratio: 1e999999
+}
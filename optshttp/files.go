@@ -0,0 +1,142 @@
+package optshttp
+
+import (
+  "fmt"
+  "io"
+  "mime/multipart"
+  "net/http"
+  "reflect"
+  "strconv"
+  "strings"
+)
+
+const(
+  flagMaxSize = "maxsize="
+  flagAccept = "accept="
+)
+
+// MaxMemory is the number of bytes of a multipart form held in memory. The
+// rest of the form is stored in temporary files, which are removed once the
+// context of the request is done. Files opened for io.ReadCloser fields are
+// closed then too. For a server request that is when the handler returns,
+// but a deadline such as that of synchttp.TimedContextHandler ends it
+// earlier, and a handler still running after it can no longer read the
+// files. A request whose context is never done, such as one made with
+// context.Background, is not cleaned up: close its files and call
+// RemoveAll on its MultipartForm yourself.
+var MaxMemory int64 = 32 << 20
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+var fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+var readCloserType = reflect.TypeOf((*io.ReadCloser)(nil)).Elem()
+
+func isFileType(t reflect.Type) bool {
+  return t == fileHeaderType || t == fileHeadersType || t == readCloserType
+}
+
+func parseSize(s string) (int64, error) {
+  mult := int64(1)
+  upper := strings.ToUpper(s)
+  for _, unit := range []struct{suffix string; mult int64}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}} {
+    if strings.HasSuffix(upper, unit.suffix) {
+      mult = unit.mult
+      s = s[:len(s) - len(unit.suffix)]
+      break
+    }
+  }
+  n, err := strconv.ParseInt(s, 10, 64)
+  if err != nil {
+    return 0, err
+  }
+  return n * mult, nil
+}
+
+// bindFiles sets a file field from the first source with uploaded files.
+func bindFiles(v reflect.Value, f *fieldPlan, sources []*source, verr *ValidationError) {
+  var tag, first *planTag
+  var req *http.Request
+  var fhs []*multipart.FileHeader
+  isRequired := false
+  for _, s := range sources {
+    t := f.tag(s.tagKey)
+    if t == nil {
+      continue
+    }
+    if first == nil || (!isRequired && t.opts.required) {
+      first = t
+    }
+    isRequired = isRequired || t.opts.required
+    if s.seen != nil {
+      s.seen[s.prefix + t.key] = true
+    }
+    if s.form != nil {
+      if fhs = s.form.File[s.prefix + t.key]; len(fhs) > 0 {
+        tag, req = t, s.req
+        break
+      }
+    }
+  }
+  if tag == nil {
+    if isRequired {
+      key := sourceFor(sources, first.tagKey).prefix + first.opts.name
+      verr.add(first.tagKey, key, &missingError{first.tagKey, key})
+    }
+    return
+  }
+
+  key := sourceFor(sources, tag.tagKey).prefix + tag.opts.name
+  if v.Type() == fileHeadersType && len(fhs) > tag.opts.limit {
+    verr.add(tag.tagKey, key, &optsError{fmt.Sprintf("list of at most %d files", tag.opts.limit), key, fmt.Sprintf("%d files", len(fhs))})
+    return
+  }
+  for _, fh := range fhs {
+    if err := checkFile(fh, tag.opts.withPrefix(sourceFor(sources, tag.tagKey).prefix)); err != nil {
+      verr.add(tag.tagKey, key, err)
+      return
+    }
+  }
+
+  switch v.Type() {
+    case fileHeaderType:
+      v.Set(reflect.ValueOf(fhs[0]))
+    case fileHeadersType:
+      v.Set(reflect.ValueOf(fhs))
+    case readCloserType:
+      f, err := fhs[0].Open()
+      if err != nil {
+        verr.add(tag.tagKey, key, &optsError{"file", key, fhs[0].Filename})
+        return
+      }
+      afterRequest(req, func() { f.Close() })
+      v.Set(reflect.ValueOf(io.ReadCloser(f)))
+  }
+}
+
+// checkFile checks an uploaded file against the maxsize= and accept= flags.
+// The accept= types are matched against the Content-Type declared by the
+// client, which is advisory: check the content itself, for example with
+// http.DetectContentType, before trusting it.
+func checkFile(fh *multipart.FileHeader, opts *tagOpts) error {
+  if len(opts.accept) > 0 {
+    contentType := strings.ToLower(fh.Header.Get("Content-Type"))
+    if i := strings.Index(contentType, ";"); i >= 0 {
+      contentType = strings.TrimSpace(contentType[:i])
+    }
+    accepted := false
+    for _, a := range opts.accept {
+      if a == contentType || (strings.HasSuffix(a, "/*") && strings.HasPrefix(contentType, a[:len(a)-1])) {
+        accepted = true
+        break
+      }
+    }
+    if !accepted {
+      return &optsError{"file type", opts.name, contentType}
+    }
+  }
+  if opts.maxSize > 0 {
+    if fh.Size > opts.maxSize {
+      return &optsError{"file size", opts.name, fmt.Sprintf("%s is larger than %d bytes", fh.Filename, opts.maxSize)}
+    }
+  }
+  return nil
+}
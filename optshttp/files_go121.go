@@ -0,0 +1,16 @@
+//go:build go1.21
+// +build go1.21
+
+package optshttp
+
+import (
+  "context"
+  "net/http"
+)
+
+// afterRequest calls f once the context of req is done, which for a server
+// request is when its handler returns or its deadline passes, whichever is
+// first. f is never called if the context cannot be done.
+func afterRequest(req *http.Request, f func()) {
+  context.AfterFunc(req.Context(), f)
+}
@@ -0,0 +1,106 @@
+// Code generated by optshttpgen. DO NOT EDIT.
+
+package optshttp_test
+
+import (
+	"github.com/istreeter/gotools/optshttp"
+	"net/http"
+)
+
+var (
+	_optshttp_genParams_form_0    = optshttp.MustTag("form", "page,default=1")
+	_optshttp_genParams_form_1    = optshttp.MustTag("form", "limit")
+	_optshttp_genParams_form_2    = optshttp.MustTag("form", "id,csv,limit=3")
+	_optshttp_genParams_form_3    = optshttp.MustTag("form", "label,required")
+	_optshttp_genParams_form_4    = optshttp.MustTag("form", "ratio")
+	_optshttp_genParams_form_5    = optshttp.MustTag("form", "draft")
+	_optshttp_genParams_form_6    = optshttp.MustTag("form", "since,layout=2006-01-02,tzparam=tz")
+	_optshttp_genParams_form_7    = optshttp.MustTag("form", "month")
+	_optshttp_genParams_form_8    = optshttp.MustTag("form", "timeout")
+	_optshttp_genParams_form_9    = optshttp.MustTag("form", "count")
+	_optshttp_genParams_path_10   = optshttp.MustTag("path", "blog")
+	_optshttp_genParams_header_11 = optshttp.MustTag("header", "user-agent")
+)
+
+func (v *genParams) UnmarshalForm(req *http.Request) error {
+	b := optshttp.NewBinder(req, "form")
+	if s, ok := b.Value(_optshttp_genParams_form_0); ok {
+		if x, ok := b.Int(_optshttp_genParams_form_0, s, 0); ok {
+			v.Page = int(x)
+		}
+	}
+	if s, ok := b.Value(_optshttp_genParams_form_1); ok {
+		if x, ok := b.Uint(_optshttp_genParams_form_1, s, 8); ok {
+			if v.Limit == nil {
+				v.Limit = new(uint8)
+			}
+			*v.Limit = uint8(x)
+		}
+	}
+	if elems, ok := b.List(_optshttp_genParams_form_2); ok {
+		v.Ids = make([]int64, len(elems))
+		for i, s := range elems {
+			x, ok := b.Int(_optshttp_genParams_form_2, s, 64)
+			if !ok {
+				break
+			}
+			v.Ids[i] = int64(x)
+		}
+	}
+	if elems, ok := b.List(_optshttp_genParams_form_3); ok {
+		v.Labels = make([]genLabel, len(elems))
+		for i, s := range elems {
+			v.Labels[i] = genLabel(s)
+		}
+	}
+	if s, ok := b.Value(_optshttp_genParams_form_4); ok {
+		if x, ok := b.Float(_optshttp_genParams_form_4, s, 32); ok {
+			v.Ratio = float32(x)
+		}
+	}
+	if s, ok := b.Value(_optshttp_genParams_form_5); ok {
+		if x, ok := b.Bool(_optshttp_genParams_form_5, s); ok {
+			if v.Draft == nil {
+				v.Draft = new(bool)
+			}
+			*v.Draft = bool(x)
+		}
+	}
+	if s, ok := b.Value(_optshttp_genParams_form_6); ok {
+		if x, ok := b.Time(_optshttp_genParams_form_6, s); ok {
+			v.Since = x
+		}
+	}
+	if s, ok := b.Value(_optshttp_genParams_form_7); ok {
+		if x, ok := b.Month(_optshttp_genParams_form_7, s); ok {
+			v.Month = x
+		}
+	}
+	if s, ok := b.Value(_optshttp_genParams_form_8); ok {
+		if x, ok := b.Duration(_optshttp_genParams_form_8, s); ok {
+			v.Timeout = x
+		}
+	}
+	if s, ok := b.Value(_optshttp_genParams_form_9); ok {
+		if x, ok := b.Uint(_optshttp_genParams_form_9, s, 0); ok {
+			v.Embedded.Count = uint(x)
+		}
+	}
+	return b.Err()
+}
+
+func (v *genParams) UnmarshalPath(req *http.Request) error {
+	b := optshttp.NewBinder(req, "path")
+	if s, ok := b.Value(_optshttp_genParams_path_10); ok {
+		v.Blog = string(s)
+	}
+	return b.Err()
+}
+
+func (v *genParams) UnmarshalHeader(req *http.Request) error {
+	b := optshttp.NewBinder(req, "header")
+	if s, ok := b.Value(_optshttp_genParams_header_11); ok {
+		v.Agent = string(s)
+	}
+	return b.Err()
+}
@@ -0,0 +1,285 @@
+package optshttp
+
+import (
+  "encoding"
+  "fmt"
+  "net/url"
+  "reflect"
+  "strconv"
+  "strings"
+  "time"
+  "github.com/gorilla/mux"
+)
+
+type FormatFunc func(v interface{}) (string, error)
+
+var formatters = make(map[reflect.Type]FormatFunc)
+
+// RegisterFormatter registers the inverse of a parser registered with
+// RegisterParser, so that values of type t can be marshalled.
+func RegisterFormatter(t reflect.Type, format FormatFunc) {
+  parsers.Lock()
+  defer parsers.Unlock()
+  formatters[t] = format
+}
+
+func lookupFormatter(t reflect.Type) (FormatFunc, bool) {
+  parsers.RLock()
+  defer parsers.RUnlock()
+  format, ok := formatters[t]
+  return format, ok
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// MarshalForm is the inverse of UnmarshalForm. Nil pointers and zero values
+// of other fields are omitted, as UnmarshalForm leaves absent fields unset,
+// unless the field has a default which would replace the zero value. An
+// element of a csv or pipe list containing the separator is an error.
+func MarshalForm(v interface{}) (url.Values, error) {
+  vals := make(url.Values)
+  err := marshalStruct(reflect.ValueOf(v), flagForm, "", 0, func(key string, strs []string) {
+    vals[key] = append(vals[key], strs...)
+  })
+  return vals, err
+}
+
+// MarshalPath is the inverse of UnmarshalPath, returning the path variables
+// of v.
+func MarshalPath(v interface{}) (map[string]string, error) {
+  vars := make(map[string]string)
+  err := marshalStruct(reflect.ValueOf(v), flagPath, "", 0, func(key string, strs []string) {
+    vars[key] = strs[0]
+  })
+  return vars, err
+}
+
+// RouteURL builds the URL of the named route from the path tags of v, with
+// a query built from the form tags of v.
+func RouteURL(router *mux.Router, name string, v interface{}) (*url.URL, error) {
+  route := router.Get(name)
+  if route == nil {
+    return nil, fmt.Errorf("No route named %s", name)
+  }
+  vars, err := MarshalPath(v)
+  if err != nil {
+    return nil, err
+  }
+  pairs := make([]string, 0, 2 * len(vars))
+  for k, val := range vars {
+    pairs = append(pairs, k, val)
+  }
+  u, err := route.URL(pairs...)
+  if err != nil {
+    return nil, err
+  }
+  return withQuery(u, v)
+}
+
+// TemplateURL builds a URL from a mux style path template such as
+// "/blogs/{blog}/posts/{post:[0-9]+}", in the same way as RouteURL.
+func TemplateURL(template string, v interface{}) (*url.URL, error) {
+  vars, err := MarshalPath(v)
+  if err != nil {
+    return nil, err
+  }
+  var path, rawPath []string
+  rest := template
+  for {
+    start := strings.Index(rest, "{")
+    if start < 0 {
+      break
+    }
+    end := strings.Index(rest[start:], "}")
+    if end < 0 {
+      return nil, fmt.Errorf("Unbalanced braces in template %s", template)
+    }
+    end += start
+    name := rest[start + 1:end]
+    if i := strings.Index(name, ":"); i >= 0 {
+      name = name[:i]
+    }
+    val, ok := vars[name]
+    if !ok {
+      return nil, fmt.Errorf("No value for %s in template %s", name, template)
+    }
+    path = append(path, rest[:start], val)
+    rawPath = append(rawPath, rest[:start], url.PathEscape(val))
+    rest = rest[end + 1:]
+  }
+  u := &url.URL{Path: strings.Join(path, "") + rest, RawPath: strings.Join(rawPath, "") + rest}
+  return withQuery(u, v)
+}
+
+func withQuery(u *url.URL, v interface{}) (*url.URL, error) {
+  query, err := MarshalForm(v)
+  if err != nil {
+    return nil, err
+  }
+  if len(query) > 0 {
+    u.RawQuery = query.Encode()
+  }
+  return u, nil
+}
+
+func marshalStruct(v reflect.Value, tagKey string, prefix string, depth int, set func(string, []string)) error {
+  for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+    if v.IsNil() {
+      return nil
+    }
+    v = v.Elem()
+  }
+  if !v.IsValid() {
+    return &targetError{nil, "must be a struct"}
+  }
+  plan, err := cachedPlan(v.Type())
+  if err != nil {
+    return err
+  }
+  for _, f := range plan.fields {
+    tag := f.tag(tagKey)
+    if tag == nil || f.kind == kindFile {
+      continue
+    }
+    field, _ := fieldByIndex(v, f.index)
+    if f.kind == kindOptional {
+      strs, err := formatOptional(field, tag.opts)
+      if err != nil {
+        return err
+      }
+      if strs != nil {
+        set(prefix + tag.opts.name, strs)
+      }
+      continue
+    }
+    if f.kind != kindValue {
+      if err := marshalNested(field, f, tagKey, prefix + tag.opts.name, depth, set); err != nil {
+        return err
+      }
+      continue
+    }
+    strs, err := formatField(field, tag.opts, tagKey == flagForm)
+    if err != nil {
+      return err
+    }
+    if len(strs) > 0 {
+      set(prefix + tag.opts.name, strs)
+    }
+  }
+  return nil
+}
+
+// formatField formats the value or list v. With omitZero, a zero value
+// without a default is left out, as an absent form parameter unmarshals to
+// it anyway. A path variable cannot be left out of a URL, so is always
+// formatted.
+func formatField(v reflect.Value, opts *tagOpts, omitZero bool) ([]string, error) {
+  if !isList(v.Type()) {
+    if omitZero && v.Kind() != reflect.Ptr && opts.def == nil && isZero(v) {
+      return nil, nil
+    }
+    str, ok, err := formatValue(v, opts)
+    if err != nil || !ok {
+      return nil, err
+    }
+    return []string{str}, nil
+  }
+  v, ok := indirect(v)
+  if !ok {
+    return nil, nil
+  }
+  var strs []string
+  for i := 0; i < v.Len(); i++ {
+    str, ok, err := formatValue(v.Index(i), opts)
+    if err != nil {
+      return nil, err
+    }
+    if len(opts.sep) > 0 && strings.Contains(str, opts.sep) {
+      return nil, fmt.Errorf("Element %q of %s contains the separator %q", str, opts.name, opts.sep)
+    }
+    if ok && len(str) > 0 {
+      strs = append(strs, str)
+    }
+  }
+  if len(opts.sep) > 0 && len(strs) > 0 {
+    strs = []string{strings.Join(strs, opts.sep)}
+  }
+  return strs, nil
+}
+
+// formatOptional formats the value of a present Optional even when it is
+// zero, and an empty Optional as a single empty string.
+func formatOptional(v reflect.Value, opts *tagOpts) ([]string, error) {
+  if !v.CanAddr() {
+    c := reflect.New(v.Type()).Elem()
+    c.Set(v)
+    v = c
+  }
+  inner, presence := v.Addr().Interface().(optional).optional()
+  switch *presence {
+    case Empty:
+      return []string{""}, nil
+    case Present:
+      if isList(inner.Type()) {
+        return formatField(inner, opts, false)
+      }
+      str, ok, err := formatValue(inner, opts)
+      if err != nil || !ok {
+        return nil, err
+      }
+      return []string{str}, nil
+  }
+  return nil, nil
+}
+
+func isZero(v reflect.Value) bool {
+  return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// formatValue is the inverse of setValue. It returns false for nil
+// pointers.
+func formatValue(v reflect.Value, opts *tagOpts) (string, bool, error) {
+  if format, ok := lookupFormatter(v.Type()); ok {
+    str, err := format(v.Interface())
+    return str, true, err
+  }
+  if v.Kind() == reflect.Ptr {
+    if v.IsNil() {
+      return "", false, nil
+    }
+    return formatValue(v.Elem(), opts)
+  }
+  if opts.decimal {
+    if str, ok := formatDecimalValue(v, opts); ok {
+      return str, true, nil
+    }
+  }
+  if v.Type() != timeType && v.Type().Implements(textMarshalerType) {
+    text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+    return string(text), true, err
+  }
+  if v.Type() != timeType && reflect.PtrTo(v.Type()).Implements(textMarshalerType) && v.CanAddr() {
+    text, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+    return string(text), true, err
+  }
+  switch v.Kind() {
+    case reflect.String:
+      return v.String(), true, nil
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+      if v.Type() == durationType {
+        return time.Duration(v.Int()).String(), true, nil
+      }
+      return strconv.FormatInt(v.Int(), 10), true, nil
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      return strconv.FormatUint(v.Uint(), 10), true, nil
+    case reflect.Float32, reflect.Float64:
+      return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true, nil
+    case reflect.Bool:
+      return strconv.FormatBool(v.Bool()), true, nil
+    case reflect.Struct:
+      if v.Type() == timeType {
+        return formatTime(opts, v.Interface().(time.Time)), true, nil
+      }
+  }
+  return "", false, &unsupportedTypeError{opts.name, v.Type()}
+}
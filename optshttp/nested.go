@@ -0,0 +1,348 @@
+package optshttp
+
+import (
+  "fmt"
+  "reflect"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+const flagPrefix = "prefix"
+
+// MaxDepth is the maximum depth of structs bound from keys such as
+// "items[0].author.name".
+var MaxDepth = 5
+
+const(
+  kindValue = iota
+  kindFile
+  kindStruct
+  kindMap
+  kindStructSlice
+  kindOptional
+)
+
+// fieldKind classifies a field as a value parsed from its key, a file, a
+// struct bound from keys under "name." (which needs the prefix flag), a map
+// bound from keys "name[k]" or a slice of structs bound from keys
+// "name[i].", or an Optional.
+func fieldKind(t reflect.Type, prefix bool) int {
+  switch {
+    case isFileType(t):
+      return kindFile
+    case prefix:
+      return kindStruct
+    case isOptional(t):
+      return kindOptional
+    case isValueType(t):
+      return kindValue
+    case t.Kind() == reflect.Map:
+      return kindMap
+    case t.Kind() == reflect.Slice && isStruct(t.Elem()) && !isValueType(t.Elem()):
+      return kindStructSlice
+  }
+  return kindValue
+}
+
+func isStruct(t reflect.Type) bool {
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+  return t.Kind() == reflect.Struct
+}
+
+// isValueType reports whether t is parsed from a single value, by a
+// registered parser or as a time, SortSpec, FilterSpec or
+// encoding.TextUnmarshaler.
+func isValueType(t reflect.Type) bool {
+  for {
+    if _, ok := lookupParser(t); ok {
+      return true
+    }
+    if t.Kind() != reflect.Ptr {
+      break
+    }
+    t = t.Elem()
+  }
+  return t == timeType || isSpecType(t) || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// separator separates the name of a struct field with the prefix flag from
+// the names of its fields, such as "author.name" or "MONGO_URL".
+func (s *source) separator() string {
+  if s.tagKey == flagEnv {
+    return "_"
+  }
+  return "."
+}
+
+func (s *source) withPrefix(prefix string) *source {
+  c := *s
+  c.prefix = prefix
+  return &c
+}
+
+// keys returns the sorted keys of the source beginning with the source's
+// prefix followed by p, with the source's prefix removed.
+func (s *source) keys(p string) []string {
+  var all []string
+  switch s.tagKey {
+    case flagForm:
+      for k := range s.req.Form {
+        all = append(all, k)
+      }
+      if s.form != nil {
+        for k := range s.form.File {
+          all = append(all, k)
+        }
+      }
+    case flagPath:
+      for k := range s.vars {
+        all = append(all, k)
+      }
+    case flagHeader:
+      for k := range s.req.Header {
+        all = append(all, k)
+      }
+    case flagCookie:
+      for k := range s.cookies {
+        all = append(all, k)
+      }
+    case flagEnv, flagFlag:
+      all = s.configKeys()
+  }
+  var keys []string
+  for _, k := range all {
+    if strings.HasPrefix(k, s.prefix + p) {
+      keys = append(keys, k[len(s.prefix):])
+    }
+  }
+  sort.Strings(keys)
+  return keys
+}
+
+func (opts *tagOpts) withPrefix(prefix string) *tagOpts {
+  if len(prefix) == 0 {
+    return opts
+  }
+  c := *opts
+  c.name = prefix + opts.name
+  return &c
+}
+
+// bindNested binds a struct, map or struct slice field from the first
+// source with keys under the field's name. A struct field which is not a
+// pointer is bound even without keys, so that its defaults are set.
+func bindNested(v reflect.Value, f *fieldPlan, sources []*source, depth int, verr *ValidationError) error {
+  var first *planTag
+  var firstSrc *source
+  var nested []*source
+  isRequired, present := false, false
+  for _, s := range sources {
+    t := f.tag(s.tagKey)
+    if t == nil {
+      continue
+    }
+    if first == nil || (!isRequired && t.opts.required) {
+      first, firstSrc = t, s
+    }
+    isRequired = isRequired || t.opts.required
+    switch f.kind {
+      case kindStruct:
+        n := s.withPrefix(s.prefix + t.key + s.separator())
+        nested = append(nested, n)
+        present = present || len(n.keys("")) > 0
+      case kindMap, kindStructSlice:
+        keys := s.keys(t.key + "[")
+        if len(keys) == 0 {
+          continue
+        }
+        if depth >= MaxDepth {
+          return depthError(s.prefix + t.opts.name, s.tagKey, verr)
+        }
+        if f.kind == kindMap {
+          return bindMap(v, t, s, keys, verr)
+        }
+        return bindStructSlice(v, t, s, keys, depth, verr)
+    }
+  }
+  if f.kind == kindStruct && (present || (!isRequired && v.Kind() != reflect.Ptr)) {
+    if depth >= MaxDepth {
+      if present {
+        return depthError(firstSrc.prefix + first.opts.name, firstSrc.tagKey, verr)
+      }
+      return nil
+    }
+    return bindStruct(v, nested, depth, verr)
+  }
+  if isRequired {
+    key := firstSrc.prefix + first.opts.name
+    verr.add(first.tagKey, key, &missingError{first.tagKey, key})
+  }
+  return nil
+}
+
+func depthError(key string, tagKey string, verr *ValidationError) error {
+  verr.add(tagKey, key, &optsError{"nesting", key, fmt.Sprintf("deeper than %d levels", MaxDepth)})
+  return nil
+}
+
+func bindStruct(v reflect.Value, sources []*source, depth int, verr *ValidationError) error {
+  for v.Kind() == reflect.Ptr {
+    if v.IsNil() {
+      v.Set(reflect.New(v.Type().Elem()))
+    }
+    v = v.Elem()
+  }
+  plan, err := cachedPlan(v.Type())
+  if err != nil {
+    return err
+  }
+  return mergeErrors(bindPlan(v, plan, sources, depth + 1), verr)
+}
+
+// mergeErrors adds the fields of a nested ValidationError to verr.
+func mergeErrors(err error, verr *ValidationError) error {
+  if nested, ok := err.(*ValidationError); ok {
+    verr.Fields = append(verr.Fields, nested.Fields...)
+    return nil
+  }
+  return err
+}
+
+// bindMap binds keys such as "filter[label]", each entry's key being parsed
+// as the map's key type and its values as the map's element type.
+func bindMap(v reflect.Value, t *planTag, s *source, keys []string, verr *ValidationError) error {
+  name := s.prefix + t.opts.name
+  var entries []string
+  for _, k := range keys {
+    rest := k[len(t.key) + 1:]
+    if i := strings.Index(rest, "]"); i == len(rest) - 1 {
+      entries = append(entries, rest[:i])
+    }
+  }
+  if len(entries) > t.opts.limit {
+    verr.add(t.tagKey, name, &optsError{fmt.Sprintf("map of at most %d entries", t.opts.limit), name, fmt.Sprintf("%d entries", len(entries))})
+    return nil
+  }
+  m := reflect.MakeMapWithSize(v.Type(), len(entries))
+  for _, entry := range entries {
+    opts := *t.opts
+    opts.name = name + "[" + entry + "]"
+    key := reflect.New(v.Type().Key()).Elem()
+    val := reflect.New(v.Type().Elem()).Elem()
+    vals := s.lookup(t.key + "[" + entry + "]")
+    var err error
+    if s.seen != nil && len(vals) > 1 && !isList(val.Type()) {
+      err = &repeatedError{t.tagKey, opts.name}
+    } else if err = setValue(key, &opts, entry); err == nil {
+      err = setField(val, &opts, vals)
+    }
+    if err != nil {
+      switch err.(type) {
+        case *optsError, *repeatedError:
+          verr.add(t.tagKey, opts.name, err)
+          continue
+      }
+      return err
+    }
+    m.SetMapIndex(key, val)
+  }
+  v.Set(m)
+  return nil
+}
+
+// bindStructSlice binds keys such as "items[0].id". Each index must be less
+// than the tag's limit, and elements at indexes without keys are left zero.
+func bindStructSlice(v reflect.Value, t *planTag, s *source, keys []string, depth int, verr *ValidationError) error {
+  name := s.prefix + t.opts.name
+  var indexes []int
+  seen := make(map[int]bool)
+  for _, k := range keys {
+    rest := k[len(t.key) + 1:]
+    end := strings.Index(rest, "].")
+    if end < 0 {
+      continue
+    }
+    i, err := strconv.Atoi(rest[:end])
+    if err != nil || i < 0 {
+      verr.add(t.tagKey, name, &optsError{"index", name, rest[:end]})
+      return nil
+    }
+    if i >= t.opts.limit {
+      verr.add(t.tagKey, name, &optsError{fmt.Sprintf("list of at most %d values", t.opts.limit), name, fmt.Sprintf("index %d", i)})
+      return nil
+    }
+    if !seen[i] {
+      seen[i] = true
+      indexes = append(indexes, i)
+    }
+  }
+  if len(indexes) == 0 {
+    return nil
+  }
+  sort.Ints(indexes)
+  n := indexes[len(indexes) - 1] + 1
+  slice := reflect.MakeSlice(v.Type(), n, n)
+  for _, i := range indexes {
+    nested := s.withPrefix(fmt.Sprintf("%s%s[%d].", s.prefix, t.key, i))
+    if err := bindStruct(slice.Index(i), []*source{nested}, depth, verr); err != nil {
+      return err
+    }
+  }
+  v.Set(slice)
+  return nil
+}
+
+// marshalNested is the inverse of bindNested.
+func marshalNested(v reflect.Value, f *fieldPlan, tagKey string, name string, depth int, set func(string, []string)) error {
+  switch v.Kind() {
+    case reflect.Ptr:
+      if v.IsNil() {
+        return nil
+      }
+    case reflect.Map, reflect.Slice:
+      if v.Len() == 0 {
+        return nil
+      }
+  }
+  if depth >= MaxDepth {
+    return fmt.Errorf("Nesting of %s is deeper than %d levels", name, MaxDepth)
+  }
+  switch f.kind {
+    case kindStruct:
+      return marshalStruct(v, tagKey, name + ".", depth + 1, set)
+    case kindMap:
+      tag := f.tag(tagKey)
+      type entry struct{
+        key string
+        strs []string
+      }
+      var entries []entry
+      for _, k := range v.MapKeys() {
+        key, _, err := formatValue(k, tag.opts)
+        if err != nil {
+          return err
+        }
+        strs, err := formatField(v.MapIndex(k), tag.opts, tagKey == flagForm)
+        if err != nil {
+          return err
+        }
+        entries = append(entries, entry{key, strs})
+      }
+      sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+      for _, e := range entries {
+        if len(e.strs) > 0 {
+          set(name + "[" + e.key + "]", e.strs)
+        }
+      }
+    case kindStructSlice:
+      for i := 0; i < v.Len(); i++ {
+        if err := marshalStruct(v.Index(i), tagKey, fmt.Sprintf("%s[%d].", name, i), depth + 1, set); err != nil {
+          return err
+        }
+      }
+  }
+  return nil
+}
@@ -0,0 +1,25 @@
+//go:build go1.18
+// +build go1.18
+
+package optshttp
+
+import (
+  "reflect"
+)
+
+// Optional is a field which also records the Presence of its key, so that
+// a key given as "?title=" can be told apart from no key at all. The Value
+// is bound as a field of type T would be.
+type Optional[T any] struct{
+  Value T
+  Presence Presence
+}
+
+func (o *Optional[T]) optional() (reflect.Value, *Presence) {
+  return reflect.ValueOf(&o.Value).Elem(), &o.Presence
+}
+
+// IsPresent reports whether the key was given with a value.
+func (o Optional[T]) IsPresent() bool {
+  return o.Presence == Present
+}
@@ -1,28 +1,145 @@
 package optshttp
 
 import (
+  "flag"
   "net/http"
   "time"
-  "github.com/gorilla/mux"
   "fmt"
   "strconv"
   "reflect"
   "strings"
+  "sync"
+  "encoding"
+  "mime/multipart"
 )
 
 const(
   flagForm = "form"
   flagPath = "path"
+  flagHeader = "header"
+  flagCookie = "cookie"
   flagInline = "inline"
+  flagCSV = "csv"
+  flagPipe = "pipe"
+  flagLimit = "limit="
+  flagRequired = "required"
+  flagDefault = "default="
 )
 
+// MaxSliceLen is the maximum number of values bound into a slice field,
+// unless the field's tag has its own limit=N flag.
+var MaxSliceLen = 100
+
+// UnmarshalForm binds fields tagged form from the request's query and body.
+// Types with an UnmarshalForm method generated by optshttpgen are bound by
+// that method instead.
 func UnmarshalForm(req *http.Request, v interface{}) error {
-  return unmarshalStruct(reflect.ValueOf(v).Elem(), flagForm, func(s string) string {return req.FormValue(s)})
+  if u, ok := v.(FormUnmarshaler); ok && useGenerated(v) {
+    return u.UnmarshalForm(req)
+  }
+  return unmarshalStruct(v, []*source{formSource(req)})
+}
+
+// UnmarshalFormStrict is UnmarshalForm, but also reports keys of the form
+// which match no tag, and keys repeated for a field which is not a list.
+func UnmarshalFormStrict(req *http.Request, v interface{}) error {
+  return unmarshalStruct(v, []*source{strictFormSource(req)})
 }
 
 func UnmarshalPath(req *http.Request, v interface{}) error {
-  vars := mux.Vars(req)
-  return unmarshalStruct(reflect.ValueOf(v).Elem(), flagPath, func(s string) string {return vars[s]})
+  if u, ok := v.(PathUnmarshaler); ok && useGenerated(v) {
+    return u.UnmarshalPath(req)
+  }
+  return UnmarshalPathWith(DefaultPathLookup, req, v)
+}
+
+func UnmarshalHeader(req *http.Request, v interface{}) error {
+  if u, ok := v.(HeaderUnmarshaler); ok && useGenerated(v) {
+    return u.UnmarshalHeader(req)
+  }
+  return unmarshalStruct(v, []*source{headerSource(req)})
+}
+
+func UnmarshalCookie(req *http.Request, v interface{}) error {
+  return unmarshalStruct(v, []*source{cookieSource(req)})
+}
+
+// Unmarshal binds path, form, header and cookie tags in a single pass. A
+// field with more than one tag takes its value from the first of those
+// sources, in that order, which has a value.
+func Unmarshal(req *http.Request, v interface{}) error {
+  return UnmarshalWith(DefaultPathLookup, req, v)
+}
+
+type source struct{
+  tagKey string
+  req *http.Request
+  vars map[string]string
+  path PathLookup
+  form *multipart.Form
+  cookies map[string][]string
+  flags *flag.FlagSet
+  prefix string
+  seen map[string]bool
+}
+
+func (s *source) lookup(key string) []string {
+  if len(s.prefix) > 0 {
+    key = s.prefix + key
+  }
+  if s.seen != nil {
+    s.seen[key] = true
+  }
+  switch s.tagKey {
+    case flagForm:
+      return s.req.Form[key]
+    case flagPath:
+      if s.vars != nil {
+        if val, ok := s.vars[key]; ok {
+          return []string{val}
+        }
+      } else if val, ok := s.path.PathVar(s.req, key); ok {
+        return []string{val}
+      }
+    case flagHeader:
+      return s.req.Header[key]
+    case flagCookie:
+      return s.cookies[key]
+    case flagEnv, flagFlag:
+      return s.lookupConfig(key)
+  }
+  return nil
+}
+
+// formSource parses the form of req, removing the temporary files of a
+// multipart form once the request finishes.
+func formSource(req *http.Request) *source {
+  if req.MultipartForm == nil {
+    if req.ParseMultipartForm(MaxMemory) == nil {
+      form := req.MultipartForm
+      afterRequest(req, func() { form.RemoveAll() })
+    }
+  }
+  return &source{tagKey: flagForm, req: req, form: req.MultipartForm}
+}
+
+func strictFormSource(req *http.Request) *source {
+  s := formSource(req)
+  s.seen = make(map[string]bool)
+  return s
+}
+
+func headerSource(req *http.Request) *source {
+  return &source{tagKey: flagHeader, req: req}
+}
+
+// cookieSource parses the cookies of req once, rather than for each field.
+func cookieSource(req *http.Request) *source {
+  cookies := make(map[string][]string)
+  for _, c := range req.Cookies() {
+    cookies[c.Name] = append(cookies[c.Name], c.Value)
+  }
+  return &source{tagKey: flagCookie, req: req, cookies: cookies}
 }
 
 type optsError struct{
@@ -35,72 +152,531 @@ func (e *optsError) Error() string {
   return fmt.Sprintf("Invalid %s %s: %s", e.optType, e.optKey, e.optVal)
 }
 
-func unmarshalStruct(v reflect.Value, tagKey string, varLookup func(string) string) error {
-  vt := v.Type()
-  numField := vt.NumField()
-  for i := 0; i < numField; i++ {
-    field := vt.Field(i)
-    if tagStr := field.Tag.Get(tagKey); len(tagStr) != 0 {
-      tagFields := strings.Split(tagStr, ",")
-      if len(tagFields) > 1 {
-        for _, flag := range tagFields[1:] {
-          switch flag {
-            case flagInline:
-              if err := unmarshalStruct(v.Field(i), tagKey, varLookup); err != nil {
-                return err
-              }
-          }
+type tagOpts struct{
+  name string
+  inline bool
+  sep string
+  trim bool
+  limit int
+  required bool
+  empty bool
+  def *string
+  prefix bool
+  maxSize int64
+  accept []string
+  fields []string
+  decimal bool
+  places int
+  prec uint
+  layout string
+  unit string
+  loc *time.Location
+  tzParam string
+}
+
+// parseTag parses a tag such as "labels,csv,limit=10,required". A default=
+// flag must come last, because everything after "default=" is the default
+// value, commas included.
+func parseTag(tagStr string) (*tagOpts, error) {
+  if i := strings.Index(tagStr, "," + flagDefault); i >= 0 {
+    def := tagStr[i + len(flagDefault) + 1:]
+    opts, err := parseTag(tagStr[:i])
+    if err != nil {
+      return nil, err
+    }
+    opts.def = &def
+    return opts, nil
+  }
+  tagFields := strings.Split(tagStr, ",")
+  opts := &tagOpts{name: tagFields[0], limit: MaxSliceLen}
+  for _, flag := range tagFields[1:] {
+    switch {
+      case flag == flagRequired:
+        opts.required = true
+      case flag == flagEmpty:
+        opts.empty = true
+      case flag == flagInline:
+        opts.inline = true
+      case flag == flagPrefix:
+        opts.prefix = true
+      case flag == flagCSV:
+        opts.sep = ","
+      case flag == flagPipe:
+        opts.sep = "|"
+      case strings.HasPrefix(flag, flagLimit):
+        limit, err := strconv.Atoi(flag[len(flagLimit):])
+        if err != nil || limit < 0 {
+          return nil, fmt.Errorf("Invalid limit in tag %q", tagStr)
+        }
+        opts.limit = limit
+      case strings.HasPrefix(flag, flagMaxSize):
+        maxSize, err := parseSize(flag[len(flagMaxSize):])
+        if err != nil || maxSize < 0 {
+          return nil, fmt.Errorf("Invalid maxsize in tag %q", tagStr)
+        }
+        opts.maxSize = maxSize
+      case flag == flagUnix || flag == flagUnixMs:
+        opts.unit = flag
+      case strings.HasPrefix(flag, flagLayout):
+        opts.layout = flag[len(flagLayout):]
+      case strings.HasPrefix(flag, flagTZ):
+        loc, err := time.LoadLocation(flag[len(flagTZ):])
+        if err != nil {
+          return nil, fmt.Errorf("Invalid tz in tag %q: %v", tagStr, err)
+        }
+        opts.loc = loc
+      case strings.HasPrefix(flag, flagTZParam):
+        opts.tzParam = flag[len(flagTZParam):]
+      case strings.HasPrefix(flag, flagDecimal):
+        places, err := strconv.Atoi(flag[len(flagDecimal):])
+        if err != nil || places < 0 {
+          return nil, fmt.Errorf("Invalid decimal in tag %q", tagStr)
+        }
+        opts.decimal, opts.places = true, places
+      case strings.HasPrefix(flag, flagPrec):
+        prec, err := strconv.ParseUint(flag[len(flagPrec):], 10, 32)
+        if err != nil {
+          return nil, fmt.Errorf("Invalid prec in tag %q", tagStr)
         }
+        opts.prec = uint(prec)
+      case strings.HasPrefix(flag, flagFields):
+        opts.fields = strings.Split(flag[len(flagFields):], "|")
+      case strings.HasPrefix(flag, flagAccept):
+        opts.accept = strings.Split(strings.ToLower(flag[len(flagAccept):]), "|")
+    }
+  }
+  return opts, nil
+}
+
+func unmarshalStruct(v interface{}, sources []*source) error {
+  rv, err := target(v)
+  if err != nil {
+    return err
+  }
+  plan, err := cachedPlan(rv.Type())
+  if err != nil {
+    return err
+  }
+  err = bindPlan(rv, plan, sources, 0)
+  for _, s := range sources {
+    if s.seen == nil {
+      continue
+    }
+    var verr ValidationError
+    if err = mergeErrors(err, &verr); err != nil {
+      return err
+    }
+    for _, key := range s.keys("") {
+      if !s.seen[key] {
+        verr.add(s.tagKey, key, &unknownError{s.tagKey, key})
       }
-      if len(tagFields) > 0 && len(tagFields[0]) > 0 {
-        if formStr := varLookup(tagFields[0]); len(formStr) > 0 {
-          if err := setValue(v.Field(i), tagFields[0], formStr); err != nil {
-            return err
-          }
+    }
+    if len(verr.Fields) > 0 {
+      err = &verr
+    }
+  }
+  return err
+}
+
+// bindPlan adds invalid and missing parameters to a ValidationError, and
+// only returns another error for problems with the struct itself, such as
+// an unsupported field type.
+func bindPlan(v reflect.Value, plan *structPlan, sources []*source, depth int) error {
+  var verr ValidationError
+  var bound []boundField
+  for _, f := range plan.fields {
+    field, parent := fieldByIndex(v, f.index)
+    switch f.kind {
+      case kindFile:
+        bindFiles(field, f, sources, &verr)
+        continue
+      case kindStruct, kindMap, kindStructSlice:
+        if err := bindNested(field, f, sources, depth, &verr); err != nil {
+          return err
         }
+        continue
+      case kindOptional:
+        var presence *Presence
+        field, presence = field.Addr().Interface().(optional).optional()
+        *presence = presenceOf(f, sources)
+    }
+    missing, tag, src, vals := lookupField(f, sources)
+    if tag == nil {
+      if missing != nil {
+        key := sourceFor(sources, missing.tagKey).prefix + missing.opts.name
+        verr.add(missing.tagKey, key, &missingError{missing.tagKey, key})
+      }
+      continue
+    }
+    opts := tag.opts.withPrefix(src.prefix)
+    if src.seen != nil && len(vals) > 1 && !isList(field.Type()) {
+      verr.add(tag.tagKey, opts.name, &repeatedError{tag.tagKey, opts.name})
+      continue
+    }
+    if len(opts.tzParam) > 0 {
+      var err error
+      if opts, err = withRequestLocation(opts, src); err != nil {
+        verr.add(tag.tagKey, opts.tzParam, err)
+        continue
+      }
+    }
+    if err := setField(field, opts, vals); err != nil {
+      if _, ok := err.(*optsError); !ok {
+        return err
       }
+      verr.add(tag.tagKey, opts.name, err)
+      continue
+    }
+    if len(f.rules) > 0 {
+      bound = append(bound, boundField{field, parent, tag.tagKey, src.prefix, opts.name, f.rules})
+    }
+  }
+  if err := validateFields(bound, &verr); err != nil {
+    return err
+  }
+  if len(verr.Fields) == 0 {
+    return nil
+  }
+  return &verr
+}
+
+// lookupField returns the tag of the first source with a value, or failing
+// that the first tag with a default. If neither is found and the field is
+// required, it returns the first required tag as missing.
+func lookupField(f *fieldPlan, sources []*source) (missing *planTag, tag *planTag, src *source, vals []string) {
+  var first, def *planTag
+  var defSrc *source
+  isRequired := false
+  for _, s := range sources {
+    t := f.tag(s.tagKey)
+    if t == nil {
+      continue
+    }
+    if len(t.opts.tzParam) > 0 && s.seen != nil {
+      s.seen[s.prefix + t.opts.tzParam] = true
+    }
+    if vals := s.lookup(t.key); hasValue(vals) || (t.opts.empty && len(vals) > 0) {
+      return nil, t, s, vals
+    }
+    if first == nil || (!isRequired && t.opts.required) {
+      first = t
+    }
+    if def == nil && t.opts.def != nil {
+      def, defSrc = t, s
+    }
+    isRequired = isRequired || t.opts.required
+  }
+  if def != nil {
+    return nil, def, defSrc, []string{*def.opts.def}
+  }
+  if isRequired {
+    return first, nil, nil, nil
+  }
+  return nil, nil, nil, nil
+}
+
+func sourceFor(sources []*source, tagKey string) *source {
+  for _, s := range sources {
+    if s.tagKey == tagKey {
+      return s
     }
   }
   return nil
 }
 
+func hasValue(vals []string) bool {
+  for _, val := range vals {
+    if len(val) > 0 {
+      return true
+    }
+  }
+  return false
+}
+
+// firstValue returns the first non-empty value, or with the empty flag the
+// first value, as hasValue considers present.
+func firstValue(vals []string, empty bool) (string, bool) {
+  for _, val := range vals {
+    if len(val) > 0 || empty {
+      return val, true
+    }
+  }
+  return "", false
+}
+
+func isList(t reflect.Type) bool {
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+  return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !isSpecType(t)
+}
+
+// setField sets a scalar field from the first non-empty value, or a slice
+// or array field from every non-empty value. With a csv or pipe flag each
+// value is further split on the separator. With the empty flag, empty values
+// are bound too.
+func setField(v reflect.Value, opts *tagOpts, vals []string) error {
+  if !isList(v.Type()) {
+    if val, ok := firstValue(vals, opts.empty); ok {
+      return setValue(v, opts, val)
+    }
+    return nil
+  }
+
+  limit := opts.limit
+  t := v.Type()
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+  if t.Kind() == reflect.Array {
+    limit = t.Len()
+  }
+  elems, err := splitList(vals, opts, limit)
+  if err != nil || len(elems) == 0 {
+    return err
+  }
+
+  for v.Kind() == reflect.Ptr {
+    if v.IsNil() {
+      v.Set(reflect.New(v.Type().Elem()))
+    }
+    v = v.Elem()
+  }
+  if v.Kind() == reflect.Slice {
+    v.Set(reflect.MakeSlice(v.Type(), len(elems), len(elems)))
+  }
+  for i, elem := range elems {
+    if err := setValue(v.Index(i), opts, elem); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// splitList returns the elements of vals split on the separator, trimmed
+// of spaces for headers such as "X-Labels: a, b", stopping at the first
+// element beyond limit so that a long value is never split in full.
+func splitList(vals []string, opts *tagOpts, limit int) ([]string, error) {
+  var elems []string
+  for _, val := range vals {
+    for more := true; more; {
+      part := val
+      more = false
+      if len(opts.sep) > 0 {
+        if i := strings.Index(val, opts.sep); i >= 0 {
+          part, val, more = val[:i], val[i + len(opts.sep):], true
+        }
+      }
+      if opts.trim {
+        part = strings.TrimSpace(part)
+      }
+      if len(part) == 0 && !opts.empty {
+        continue
+      }
+      if len(elems) == limit {
+        return nil, &optsError{listLimit(limit), opts.name, fmt.Sprintf("more than %d values", limit)}
+      }
+      elems = append(elems, part)
+    }
+  }
+  return elems, nil
+}
+
+func listLimit(limit int) string {
+  return fmt.Sprintf("list of at most %d values", limit)
+}
+
 var timeType = reflect.TypeOf(time.Time{})
 var monthType = reflect.TypeOf(time.Month(1))
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type unsupportedTypeError struct{
+  optKey string
+  t reflect.Type
+}
+
+func (e *unsupportedTypeError) Error() string {
+  return fmt.Sprintf("Unsupported type %s for %s", e.t, e.optKey)
+}
+
+type ParseFunc func(s string) (interface{}, error)
+
+var parsers = struct{
+  sync.RWMutex
+  m map[reflect.Type]ParseFunc
+  version int
+}{m: make(map[reflect.Type]ParseFunc)}
+
+// RegisterParser registers a func to parse values of type t. Registered
+// parsers take precedence over encoding.TextUnmarshaler and the built-in
+// types. The parsed value must be assignable to t.
+func RegisterParser(t reflect.Type, parse ParseFunc) {
+  parsers.Lock()
+  defer parsers.Unlock()
+  parsers.m[t] = parse
+  parsers.version++
+}
 
-func setValue(v reflect.Value, formKey string, formStr string) error {
+func lookupParser(t reflect.Type) (ParseFunc, bool) {
+  parsers.RLock()
+  defer parsers.RUnlock()
+  parse, ok := parsers.m[t]
+  return parse, ok
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func setValue(v reflect.Value, opts *tagOpts, formStr string) error {
+  formKey := opts.name
+  if parse, ok := lookupParser(v.Type()); ok {
+    val, err := parse(formStr)
+    if err != nil {
+      return &optsError{v.Type().String(), formKey, formStr}
+    }
+    rv := reflect.ValueOf(val)
+    if !rv.IsValid() || !rv.Type().AssignableTo(v.Type()) {
+      return fmt.Errorf("Parser for %s returned %T", v.Type(), val)
+    }
+    v.Set(rv)
+    return nil
+  }
+  if isSpecType(v.Type()) {
+    return setSpec(v, opts, formStr)
+  }
+  if isBigType(v.Type()) && v.CanAddr() {
+    return setBig(v, opts, formStr)
+  }
+  if opts.decimal && v.Kind() != reflect.Ptr {
+    switch v.Kind() {
+      case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+          reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        if v.Type() != durationType && v.Type() != monthType {
+          return setDecimalInt(v, opts, formStr)
+        }
+    }
+  }
+  if v.Kind() != reflect.Ptr && v.Type() != timeType && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+    if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+      if err := u.UnmarshalText([]byte(formStr)); err != nil {
+        return &optsError{v.Type().String(), formKey, formStr}
+      }
+      return nil
+    }
+  }
   switch v.Kind() {
     case reflect.String:
       v.SetString(formStr)
-    case reflect.Int:
-      val, err := strconv.ParseInt(formStr, 10, 0)
-      if  err != nil{
-        return &optsError{"integer", formKey, formStr}     
-      }
-      if v.Type() == monthType {
-        if val < 0 || val > 12 {
-          return &optsError{"date", formKey, formStr}     
-        }
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+      var val int64
+      var err error
+      switch v.Type() {
+        case durationType:
+          var d time.Duration
+          d, err = parseDuration(opts, formStr)
+          val = int64(d)
+        case monthType:
+          var m time.Month
+          m, err = parseMonth(opts, formStr)
+          val = int64(m)
+        default:
+          val, err = parseInt(opts, formStr, v.Type().Bits())
+      }
+      if err != nil {
+        return err
       }
       v.SetInt(val)
-    case reflect.Uint:
-      val, err := strconv.ParseUint(formStr, 10, 0)
-      if err != nil{
-        return &optsError{"unsigned integer", formKey, formStr}     
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      val, err := parseUint(opts, formStr, v.Type().Bits())
+      if err != nil {
+        return err
       }
       v.SetUint(val)
+    case reflect.Float32, reflect.Float64:
+      val, err := parseFloat(opts, formStr, v.Type().Bits())
+      if err != nil {
+        return err
+      }
+      v.SetFloat(val)
+    case reflect.Bool:
+      val, err := parseBool(opts, formStr)
+      if err != nil {
+        return err
+      }
+      v.SetBool(val)
     case reflect.Struct:
-      if v.Type() == timeType {
-        t := &time.Time{}
-        if err := t.UnmarshalText([]byte(formStr)); err != nil {
-          return &optsError{"time in RFC3339 format", formKey, formStr}     
-        }
-        v.Set(reflect.ValueOf(*t))
+      if v.Type() != timeType {
+        return &unsupportedTypeError{formKey, v.Type()}
+      }
+      t, err := parseTime(opts, formStr)
+      if err != nil {
+        return err
       }
+      v.Set(reflect.ValueOf(t))
     case reflect.Ptr:
       if v.IsNil() {
-          v.Set(reflect.New(v.Type().Elem()))
+        p := reflect.New(v.Type().Elem())
+        if err := setValue(p.Elem(), opts, formStr); err != nil {
+          return err
+        }
+        v.Set(p)
+        return nil
       }
-      return setValue(v.Elem(), formKey, formStr)
+      return setValue(v.Elem(), opts, formStr)
+    default:
+      return &unsupportedTypeError{formKey, v.Type()}
   }
   return nil
 }
+
+func parseInt(opts *tagOpts, str string, bits int) (int64, error) {
+  val, err := strconv.ParseInt(str, 10, bits)
+  if err != nil {
+    return 0, &optsError{"integer", opts.name, str}
+  }
+  return val, nil
+}
+
+func parseUint(opts *tagOpts, str string, bits int) (uint64, error) {
+  val, err := strconv.ParseUint(str, 10, bits)
+  if err != nil {
+    return 0, &optsError{"unsigned integer", opts.name, str}
+  }
+  return val, nil
+}
+
+func parseFloat(opts *tagOpts, str string, bits int) (float64, error) {
+  val, err := strconv.ParseFloat(str, bits)
+  if err != nil {
+    return 0, &optsError{"number", opts.name, str}
+  }
+  return val, nil
+}
+
+func parseBool(opts *tagOpts, str string) (bool, error) {
+  switch strings.ToLower(str) {
+    case "true", "1", "on":
+      return true, nil
+    case "false", "0", "off":
+      return false, nil
+  }
+  return false, &optsError{"boolean", opts.name, str}
+}
+
+func parseDuration(opts *tagOpts, str string) (time.Duration, error) {
+  val, err := time.ParseDuration(str)
+  if err != nil {
+    return 0, &optsError{"duration", opts.name, str}
+  }
+  return val, nil
+}
+
+func parseMonth(opts *tagOpts, str string) (time.Month, error) {
+  val, err := strconv.ParseInt(str, 10, 64)
+  if err != nil {
+    return 0, &optsError{"integer", opts.name, str}
+  }
+  if val < 0 || val > 12 {
+    return 0, &optsError{"date", opts.name, str}
+  }
+  return time.Month(val), nil
+}
@@ -0,0 +1,435 @@
+// Command optshttpgen generates UnmarshalForm, UnmarshalPath and
+// UnmarshalHeader methods which bind the tagged fields of a struct without
+// reflection. optshttp.UnmarshalForm and friends call the generated methods
+// in place of their reflective binding. Use it with go:generate:
+//
+//   //go:generate optshttpgen -type=Params
+//
+// Fields which only the reflective binding supports, such as validate
+// tags, nested structs, files and types with an UnmarshalText method, are
+// reported as errors instead of being generated with different behaviour.
+// While a parser registered with optshttp.RegisterParser applies to a field
+// of the type, optshttp.UnmarshalForm and friends use the reflective binding
+// instead of the generated methods.
+package main
+
+import (
+  "bytes"
+  "flag"
+  "fmt"
+  "go/ast"
+  "go/format"
+  "go/parser"
+  "go/printer"
+  "go/token"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "reflect"
+  "strconv"
+  "strings"
+  "github.com/istreeter/gotools/optshttp"
+)
+
+var methods = []struct{
+  tagKey string
+  name string
+}{
+  {"form", "UnmarshalForm"},
+  {"path", "UnmarshalPath"},
+  {"header", "UnmarshalHeader"},
+}
+
+var unsupportedFlags = []string{"prefix", "maxsize=", "accept=", "fields=", "decimal=", "prec="}
+
+var intBits = map[string]int{
+  "int": 0, "int8": 8, "int16": 16, "int32": 32, "int64": 64,
+  "uint": 0, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64,
+  "float32": 32, "float64": 64,
+}
+
+func main() {
+  typeNames := flag.String("type", "", "comma-separated names of the struct types")
+  output := flag.String("output", "", "output file; default <type>_optshttp.go")
+  flag.Parse()
+  if len(*typeNames) == 0 {
+    fmt.Fprintln(os.Stderr, "usage: optshttpgen -type=T [-output=file] [files]")
+    os.Exit(2)
+  }
+  types := strings.Split(*typeNames, ",")
+  if len(*output) == 0 {
+    *output = strings.ToLower(types[0]) + "_optshttp.go"
+  }
+
+  files := flag.Args()
+  if len(files) == 0 {
+    var err error
+    if files, err = packageFiles(".", *output); err != nil {
+      fail(err)
+    }
+  }
+  p, err := parsePackage(files)
+  if err != nil {
+    fail(err)
+  }
+  src, err := p.generate(types)
+  if err != nil {
+    fail(err)
+  }
+  if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+    fail(err)
+  }
+}
+
+func fail(err error) {
+  fmt.Fprintln(os.Stderr, "optshttpgen:", err)
+  os.Exit(1)
+}
+
+// packageFiles lists the non-test Go files of dir, other than output.
+func packageFiles(dir string, output string) ([]string, error) {
+  matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+  if err != nil {
+    return nil, err
+  }
+  var files []string
+  for _, m := range matches {
+    if !strings.HasSuffix(m, "_test.go") && filepath.Base(m) != filepath.Base(output) {
+      files = append(files, m)
+    }
+  }
+  return files, nil
+}
+
+type pkg struct{
+  name string
+  fset *token.FileSet
+  types map[string]ast.Expr
+  textUnmarshalers map[string]bool
+}
+
+func parsePackage(files []string) (*pkg, error) {
+  p := &pkg{
+    fset: token.NewFileSet(),
+    types: make(map[string]ast.Expr),
+    textUnmarshalers: make(map[string]bool),
+  }
+  for _, file := range files {
+    f, err := parser.ParseFile(p.fset, file, nil, 0)
+    if err != nil {
+      return nil, err
+    }
+    p.name = f.Name.Name
+    ast.Inspect(f, func(n ast.Node) bool {
+      switch n := n.(type) {
+        case *ast.TypeSpec:
+          p.types[n.Name.Name] = n.Type
+        case *ast.FuncDecl:
+          if n.Recv != nil && n.Name.Name == "UnmarshalText" {
+            recv := n.Recv.List[0].Type
+            if star, ok := recv.(*ast.StarExpr); ok {
+              recv = star.X
+            }
+            if id, ok := recv.(*ast.Ident); ok {
+              p.textUnmarshalers[id.Name] = true
+            }
+          }
+      }
+      return true
+    })
+  }
+  return p, nil
+}
+
+type gen struct{
+  p *pkg
+  typeName string
+  vars bytes.Buffer
+  body bytes.Buffer
+  n int
+  usesTime bool
+}
+
+func (p *pkg) generate(typeNames []string) ([]byte, error) {
+  var vars, methodsSrc bytes.Buffer
+  usesTime := false
+  for _, typeName := range typeNames {
+    st, ok := p.types[typeName].(*ast.StructType)
+    if !ok {
+      return nil, fmt.Errorf("%s is not a struct type", typeName)
+    }
+    g := &gen{p: p, typeName: typeName}
+    for _, m := range methods {
+      g.body.Reset()
+      if err := g.fields(st, "v", m.tagKey, 0); err != nil {
+        return nil, err
+      }
+      if g.body.Len() == 0 {
+        continue
+      }
+      fmt.Fprintf(&methodsSrc, "\nfunc (v *%s) %s(req *http.Request) error {\n", typeName, m.name)
+      fmt.Fprintf(&methodsSrc, "b := optshttp.NewBinder(req, %q)\n", m.tagKey)
+      methodsSrc.Write(g.body.Bytes())
+      methodsSrc.WriteString("return b.Err()\n}\n")
+    }
+    vars.Write(g.vars.Bytes())
+    usesTime = usesTime || g.usesTime
+  }
+
+  var src bytes.Buffer
+  src.WriteString("// Code generated by optshttpgen. DO NOT EDIT.\n\n")
+  fmt.Fprintf(&src, "package %s\n\nimport (\n\"net/http\"\n", p.name)
+  if usesTime {
+    src.WriteString("\"time\"\n")
+  }
+  src.WriteString("\"github.com/istreeter/gotools/optshttp\"\n)\n\nvar (\n")
+  src.Write(vars.Bytes())
+  src.WriteString(")\n")
+  src.Write(methodsSrc.Bytes())
+  return format.Source(src.Bytes())
+}
+
+// fields generates the binding of the fields of st tagged with tagKey,
+// following inline structs as the reflective binding does.
+func (g *gen) fields(st *ast.StructType, access string, tagKey string, depth int) error {
+  if depth > optshttp.MaxDepth {
+    return fmt.Errorf("%s: inline structs nested too deeply", access)
+  }
+  for _, field := range st.Fields.List {
+    if field.Tag == nil {
+      continue
+    }
+    tagLit, err := strconv.Unquote(field.Tag.Value)
+    if err != nil {
+      return err
+    }
+    tag := reflect.StructTag(tagLit)
+    tagStr := tag.Get(tagKey)
+    if len(tagStr) == 0 {
+      continue
+    }
+    names := field.Names
+    if len(names) == 0 {
+      names = []*ast.Ident{embeddedName(field.Type)}
+    }
+    for _, name := range names {
+      if name == nil {
+        return fmt.Errorf("%s: unsupported embedded field", access)
+      }
+      if err := g.field(field.Type, access + "." + name.Name, tagKey, tagStr, tag, depth); err != nil {
+        return err
+      }
+    }
+  }
+  return nil
+}
+
+func embeddedName(expr ast.Expr) *ast.Ident {
+  switch t := expr.(type) {
+    case *ast.Ident:
+      return t
+    case *ast.SelectorExpr:
+      return t.Sel
+  }
+  return nil
+}
+
+func (g *gen) field(expr ast.Expr, path string, tagKey string, tagStr string, tag reflect.StructTag, depth int) error {
+  flagsStr := tagStr
+  if i := strings.Index(flagsStr, ",default="); i >= 0 {
+    flagsStr = flagsStr[:i]
+  }
+  flags := strings.Split(flagsStr, ",")
+  for _, f := range flags[1:] {
+    for _, u := range unsupportedFlags {
+      if f == u || (strings.HasSuffix(u, "=") && strings.HasPrefix(f, u)) {
+        return fmt.Errorf("%s: the %s flag is not supported", path, strings.TrimSuffix(u, "="))
+      }
+    }
+    if f == "inline" {
+      st, ok := g.structType(expr)
+      if !ok {
+        return fmt.Errorf("%s: inline field is not a struct", path)
+      }
+      if err := g.fields(st, path, tagKey, depth + 1); err != nil {
+        return err
+      }
+    }
+  }
+  if len(flags[0]) == 0 {
+    return nil
+  }
+  if len(tag.Get("validate")) > 0 {
+    return fmt.Errorf("%s: validate tags are not supported", path)
+  }
+  if err := checkTag(tagKey, tagStr); err != nil {
+    return fmt.Errorf("%s: %v", path, err)
+  }
+
+  ptr, slice, elemPtr := false, false, false
+  elem := expr
+  if star, ok := elem.(*ast.StarExpr); ok {
+    ptr, elem = true, star.X
+  }
+  if arr, ok := elem.(*ast.ArrayType); ok && arr.Len == nil && !ptr {
+    slice, elem = true, arr.Elt
+    if star, ok := elem.(*ast.StarExpr); ok {
+      elemPtr, elem = true, star.X
+    }
+  }
+  base, conv, err := g.baseType(elem)
+  if err != nil {
+    return fmt.Errorf("%s: %v", path, err)
+  }
+
+  tagVar := fmt.Sprintf("_optshttp_%s_%s_%d", g.typeName, tagKey, g.n)
+  g.n++
+  fmt.Fprintf(&g.vars, "%s = optshttp.MustTag(%q, %q)\n", tagVar, tagKey, tagStr)
+
+  parse, val := parseExpr(base, conv, tagVar)
+  if !slice {
+    fmt.Fprintf(&g.body, "if s, ok := b.Value(%s); ok {\n", tagVar)
+    if len(parse) > 0 {
+      fmt.Fprintf(&g.body, "if %s; ok {\n", parse)
+    }
+    g.assign(path, val, ptr, elem)
+    if len(parse) > 0 {
+      g.body.WriteString("}\n")
+    }
+    g.body.WriteString("}\n")
+    return nil
+  }
+  fmt.Fprintf(&g.body, "if elems, ok := b.List(%s); ok {\n", tagVar)
+  fmt.Fprintf(&g.body, "%s = make(%s, len(elems))\n", path, g.typeString(expr))
+  g.body.WriteString("for i, s := range elems {\n")
+  if len(parse) > 0 {
+    fmt.Fprintf(&g.body, "%s\nif !ok {\nbreak\n}\n", parse)
+  }
+  g.assign(path + "[i]", val, elemPtr, elem)
+  g.body.WriteString("}\n}\n")
+  return nil
+}
+
+// assign sets a pointer field through the pointer, allocating it if it is
+// nil, as the reflective binding does.
+func (g *gen) assign(path string, val string, ptr bool, elem ast.Expr) {
+  if ptr {
+    fmt.Fprintf(&g.body, "if %s == nil {\n%s = new(%s)\n}\n*%s = %s\n", path, path, g.typeString(elem), path, val)
+  } else {
+    fmt.Fprintf(&g.body, "%s = %s\n", path, val)
+  }
+}
+
+// parseExpr returns the statement parsing s, if any, and the value to
+// assign.
+func parseExpr(base string, conv string, tagVar string) (string, string) {
+  convert := func(x string) string {
+    if len(conv) == 0 {
+      return x
+    }
+    return conv + "(" + x + ")"
+  }
+  switch base {
+    case "string":
+      return "", convert("s")
+    case "bool":
+      return fmt.Sprintf("x, ok := b.Bool(%s, s)", tagVar), convert("x")
+    case "duration":
+      return fmt.Sprintf("x, ok := b.Duration(%s, s)", tagVar), convert("x")
+    case "month":
+      return fmt.Sprintf("x, ok := b.Month(%s, s)", tagVar), convert("x")
+    case "time":
+      return fmt.Sprintf("x, ok := b.Time(%s, s)", tagVar), "x"
+    case "float32", "float64":
+      return fmt.Sprintf("x, ok := b.Float(%s, s, %d)", tagVar, intBits[base]), convert("x")
+  }
+  if strings.HasPrefix(base, "uint") {
+    return fmt.Sprintf("x, ok := b.Uint(%s, s, %d)", tagVar, intBits[base]), convert("x")
+  }
+  return fmt.Sprintf("x, ok := b.Int(%s, s, %d)", tagVar, intBits[base]), convert("x")
+}
+
+// baseType resolves a field type to the kind of value parsed, and the
+// conversion needed to assign it.
+func (g *gen) baseType(expr ast.Expr) (base string, conv string, err error) {
+  switch t := expr.(type) {
+    case *ast.Ident:
+      if _, ok := intBits[t.Name]; ok || t.Name == "string" || t.Name == "bool" {
+        return t.Name, t.Name, nil
+      }
+      if g.p.textUnmarshalers[t.Name] {
+        return "", "", fmt.Errorf("type %s has an UnmarshalText method", t.Name)
+      }
+      underlying, ok := g.p.types[t.Name]
+      if !ok {
+        return "", "", fmt.Errorf("unsupported type %s", t.Name)
+      }
+      base, _, err := g.baseType(underlying)
+      if err != nil {
+        return "", "", err
+      }
+      switch base {
+        case "time":
+          return "", "", fmt.Errorf("unsupported type %s", t.Name)
+        case "duration", "month":
+          // Only time.Duration and time.Month themselves are parsed
+          // specially, other types of the same kind are integers.
+          base = "int64"
+      }
+      return base, t.Name, nil
+    case *ast.SelectorExpr:
+      if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" {
+        switch t.Sel.Name {
+          case "Time":
+            return "time", "", nil
+          case "Duration":
+            return "duration", "", nil
+          case "Month":
+            return "month", "", nil
+        }
+      }
+  }
+  return "", "", fmt.Errorf("unsupported type %s", g.exprString(expr))
+}
+
+func (g *gen) structType(expr ast.Expr) (*ast.StructType, bool) {
+  switch t := expr.(type) {
+    case *ast.StructType:
+      return t, true
+    case *ast.Ident:
+      st, ok := g.p.types[t.Name].(*ast.StructType)
+      return st, ok
+  }
+  return nil, false
+}
+
+func (g *gen) exprString(expr ast.Expr) string {
+  var buf bytes.Buffer
+  printer.Fprint(&buf, g.p.fset, expr)
+  return buf.String()
+}
+
+// typeString is exprString for a type written into the generated code,
+// recording whether it refers to the time package, which must then be
+// imported.
+func (g *gen) typeString(expr ast.Expr) string {
+  ast.Inspect(expr, func(n ast.Node) bool {
+    if sel, ok := n.(*ast.SelectorExpr); ok {
+      if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "time" {
+        g.usesTime = true
+      }
+    }
+    return true
+  })
+  return g.exprString(expr)
+}
+
+func checkTag(tagKey string, tagStr string) (err error) {
+  defer func() {
+    if r := recover(); r != nil {
+      err = fmt.Errorf("%v", r)
+    }
+  }()
+  optshttp.MustTag(tagKey, tagStr)
+  return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+  "bytes"
+  "flag"
+  "io/ioutil"
+  "path/filepath"
+  "testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestGenerateGolden(t *testing.T) {
+  tests := []struct{
+    name string
+    types []string
+  }{
+    {"uptime", []string{"statusParams"}},
+    {"uptime_time", []string{"statusParams", "timeParams"}},
+  }
+  for _, test := range tests {
+    p, err := parsePackage([]string{filepath.Join("testdata", "uptime.go")})
+    if err != nil {
+      t.Fatal(err)
+    }
+    got, err := p.generate(test.types)
+    if err != nil {
+      t.Fatalf("%s: %v", test.name, err)
+    }
+    golden := filepath.Join("testdata", test.name + ".golden")
+    if *update {
+      if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+        t.Fatal(err)
+      }
+      continue
+    }
+    want, err := ioutil.ReadFile(golden)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if !bytes.Equal(got, want) {
+      t.Errorf("%s: generated\n%s\nwant\n%s", test.name, got, want)
+    }
+  }
+}
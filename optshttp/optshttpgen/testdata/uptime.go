@@ -0,0 +1,17 @@
+package uptime
+
+import (
+  "time"
+)
+
+type statusParams struct {
+  Uptime struct {
+    Seconds int `form:"seconds"`
+  } `form:",inline"`
+  Since time.Time `form:"since"`
+}
+
+type timeParams struct {
+  Until *time.Time      `form:"until"`
+  Waits []time.Duration `form:"wait"`
+}
@@ -0,0 +1,69 @@
+package optshttp
+
+import (
+  "net/http"
+  "github.com/gorilla/mux"
+)
+
+// PathLookup looks up the path variables of a request, so that path tags
+// can be used with any router.
+type PathLookup interface{
+  PathVar(req *http.Request, name string) (string, bool)
+}
+
+// PathLookupFunc adapts a func, such as one reading a router's params from
+// the request context, to PathLookup.
+type PathLookupFunc func(req *http.Request, name string) (string, bool)
+
+func (f PathLookupFunc) PathVar(req *http.Request, name string) (string, bool) {
+  return f(req, name)
+}
+
+// pathVarser is implemented by lookups which can also list the variables of
+// a request, as needed for path tags on nested structs, maps and slices.
+type pathVarser interface{
+  PathVars(req *http.Request) map[string]string
+}
+
+type muxVars struct{}
+
+func (muxVars) PathVar(req *http.Request, name string) (string, bool) {
+  val, ok := mux.Vars(req)[name]
+  return val, ok
+}
+
+func (muxVars) PathVars(req *http.Request) map[string]string {
+  return mux.Vars(req)
+}
+
+// MuxVars looks up path variables set by a gorilla/mux router.
+var MuxVars PathLookup = muxVars{}
+
+// DefaultPathLookup is used by UnmarshalPath and Unmarshal. Set it before
+// serving any requests.
+var DefaultPathLookup PathLookup = MuxVars
+
+// UnmarshalPathWith is UnmarshalPath with the path variables looked up by
+// lookup instead of DefaultPathLookup.
+func UnmarshalPathWith(lookup PathLookup, req *http.Request, v interface{}) error {
+  return unmarshalStruct(v, []*source{pathSource(req, lookup)})
+}
+
+// UnmarshalWith is Unmarshal with the path variables looked up by lookup
+// instead of DefaultPathLookup.
+func UnmarshalWith(lookup PathLookup, req *http.Request, v interface{}) error {
+  return unmarshalStruct(v, []*source{
+    pathSource(req, lookup),
+    formSource(req),
+    headerSource(req),
+    cookieSource(req),
+  })
+}
+
+func pathSource(req *http.Request, lookup PathLookup) *source {
+  s := &source{tagKey: flagPath, req: req, path: lookup}
+  if l, ok := lookup.(pathVarser); ok {
+    s.vars = l.PathVars(req)
+  }
+  return s
+}
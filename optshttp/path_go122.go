@@ -0,0 +1,15 @@
+//go:build go1.22
+// +build go1.22
+
+package optshttp
+
+import (
+  "net/http"
+)
+
+// PathValue looks up path variables matched by the patterns of the Go 1.22
+// http.ServeMux, such as "/blogs/{blog}". An empty value counts as absent.
+var PathValue PathLookup = PathLookupFunc(func(req *http.Request, name string) (string, bool) {
+  val := req.PathValue(name)
+  return val, len(val) > 0
+})
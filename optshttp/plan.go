@@ -0,0 +1,167 @@
+package optshttp
+
+import (
+  "fmt"
+  "net/http"
+  "reflect"
+  "sync"
+)
+
+// tagKeys are the tags a plan is compiled for, in the order of precedence
+// used by Unmarshal and UnmarshalConfig.
+var tagKeys = []string{flagPath, flagForm, flagHeader, flagCookie, flagFlag, flagEnv}
+
+// structPlan is the compiled form of a struct type's tags, cached so that
+// tags are parsed and the type walked only once per type.
+type structPlan struct{
+  fields []*fieldPlan
+}
+
+type fieldPlan struct{
+  index []int
+  kind int
+  tags []*planTag
+  rules []rule
+}
+
+type planTag struct{
+  tagKey string
+  key string
+  opts *tagOpts
+}
+
+func (f *fieldPlan) tag(tagKey string) *planTag {
+  for _, t := range f.tags {
+    if t.tagKey == tagKey {
+      return t
+    }
+  }
+  return nil
+}
+
+type planEntry struct{
+  plan *structPlan
+  err error
+}
+
+var plans = struct{
+  sync.RWMutex
+  m map[reflect.Type]*planEntry
+}{m: make(map[reflect.Type]*planEntry)}
+
+func cachedPlan(t reflect.Type) (*structPlan, error) {
+  plans.RLock()
+  e, ok := plans.m[t]
+  plans.RUnlock()
+  if ok {
+    return e.plan, e.err
+  }
+  plan, err := compilePlan(t)
+  plans.Lock()
+  plans.m[t] = &planEntry{plan, err}
+  plans.Unlock()
+  return plan, err
+}
+
+func compilePlan(t reflect.Type) (*structPlan, error) {
+  if t.Kind() != reflect.Struct {
+    return nil, &targetError{t, "not a struct"}
+  }
+  fields, err := compileFields(t, nil, tagKeys)
+  if err != nil {
+    return nil, err
+  }
+  return &structPlan{fields}, nil
+}
+
+// compileFields flattens the fields of t tagged with any of keys. Fields of
+// an inline struct are included only for the keys whose tag is inline.
+func compileFields(t reflect.Type, index []int, keys []string) ([]*fieldPlan, error) {
+  var fields []*fieldPlan
+  numField := t.NumField()
+  for i := 0; i < numField; i++ {
+    field := t.Field(i)
+    fieldIndex := append(index[:len(index):len(index)], i)
+    f := &fieldPlan{index: fieldIndex}
+    var inlineKeys []string
+    prefix := false
+    for _, tagKey := range keys {
+      tagStr := field.Tag.Get(tagKey)
+      if len(tagStr) == 0 {
+        continue
+      }
+      opts, err := parseTag(tagStr)
+      if err != nil {
+        return nil, err
+      }
+      if opts.inline {
+        inlineKeys = append(inlineKeys, tagKey)
+      }
+      if opts.prefix && tagKey == flagHeader {
+        // Nested keys such as X-Author.Name are canonicalized to X-Author.name
+        // by net/http, so could never be matched.
+        return nil, fmt.Errorf("Field %s has prefix flag on a header tag", field.Name)
+      }
+      prefix = prefix || opts.prefix
+      if err := checkSpecTag(field.Type, field.Name, opts); err != nil {
+        return nil, err
+      }
+      if err := checkBigTag(field.Type, field.Name, opts); err != nil {
+        return nil, err
+      }
+      if len(opts.name) > 0 {
+        key := opts.name
+        if tagKey == flagHeader {
+          key = http.CanonicalHeaderKey(key)
+          opts.trim = true
+        }
+        f.tags = append(f.tags, &planTag{tagKey, key, opts})
+      }
+    }
+    if (len(f.tags) > 0 || len(inlineKeys) > 0) && len(field.PkgPath) > 0 && !(field.Anonymous && len(f.tags) == 0) {
+      return nil, fmt.Errorf("Field %s of %s is tagged but unexported", field.Name, t)
+    }
+    if len(inlineKeys) > 0 {
+      if field.Type.Kind() != reflect.Struct {
+        return nil, fmt.Errorf("Field %s of %s is inline but not a struct", field.Name, t)
+      }
+      inlined, err := compileFields(field.Type, fieldIndex, inlineKeys)
+      if err != nil {
+        return nil, err
+      }
+      fields = append(fields, inlined...)
+    }
+    if len(f.tags) == 0 {
+      continue
+    }
+    f.kind = fieldKind(field.Type, prefix)
+    if f.kind == kindStruct && !isStruct(field.Type) {
+      return nil, fmt.Errorf("Field %s has prefix flag but is not a struct", field.Name)
+    }
+    if validateStr := field.Tag.Get(flagValidate); len(validateStr) > 0 {
+      rules, err := parseValidate(validateStr)
+      if err != nil {
+        return nil, err
+      }
+      if err := resolveOrder(t, field, rules); err != nil {
+        return nil, err
+      }
+      if err := compileRules(field, rules); err != nil {
+        return nil, err
+      }
+      f.rules = rules
+    }
+    fields = append(fields, f)
+  }
+  return fields, nil
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex, also returning the struct
+// containing the field.
+func fieldByIndex(v reflect.Value, index []int) (field reflect.Value, parent reflect.Value) {
+  parent = v
+  for _, i := range index[:len(index) - 1] {
+    parent = parent.Field(i)
+  }
+  return parent.Field(index[len(index) - 1]), parent
+}
@@ -0,0 +1,69 @@
+package optshttp
+
+import (
+  "reflect"
+)
+
+const flagEmpty = "empty"
+
+// Presence records whether the key of an Optional field was absent, given
+// with only empty values, or given with a value.
+type Presence int
+
+const(
+  Absent Presence = iota
+  Empty
+  Present
+)
+
+func (p Presence) String() string {
+  switch p {
+    case Empty:
+      return "empty"
+    case Present:
+      return "present"
+  }
+  return "absent"
+}
+
+// optional is implemented by *Optional[T], so that fields of any Optional
+// type are recognised without generics.
+type optional interface{
+  optional() (reflect.Value, *Presence)
+}
+
+var optionalType = reflect.TypeOf((*optional)(nil)).Elem()
+
+func isOptional(t reflect.Type) bool {
+  return t.Kind() == reflect.Struct && reflect.PtrTo(t).Implements(optionalType)
+}
+
+// unwrapOptional returns the value of an Optional, or v itself if v is not
+// an Optional.
+func unwrapOptional(v reflect.Value) reflect.Value {
+  if v.CanAddr() && isOptional(v.Type()) {
+    inner, _ := v.Addr().Interface().(optional).optional()
+    return inner
+  }
+  return v
+}
+
+// presenceOf reports the presence of f's key in the first source which has
+// it. A default does not make an absent key present.
+func presenceOf(f *fieldPlan, sources []*source) Presence {
+  p := Absent
+  for _, s := range sources {
+    t := f.tag(s.tagKey)
+    if t == nil {
+      continue
+    }
+    vals := s.lookup(t.key)
+    if hasValue(vals) {
+      return Present
+    }
+    if len(vals) > 0 {
+      p = Empty
+    }
+  }
+  return p
+}
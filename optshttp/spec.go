@@ -0,0 +1,158 @@
+package optshttp
+
+import (
+  "fmt"
+  "reflect"
+  "strings"
+)
+
+const flagFields = "fields="
+
+// SortField is a field to sort by, in descending order if Desc.
+type SortField struct{
+  Field string
+  Desc bool
+}
+
+// SortSpec is a field type parsed from a sort expression such as
+// "-published,title", sorting by each field in turn. A field is descending
+// if prefixed with "-". Field names must be listed by the tag's fields flag,
+// as in form:"sort,fields=published|title".
+type SortSpec []SortField
+
+func (s SortSpec) MarshalText() ([]byte, error) {
+  strs := make([]string, len(s))
+  for i, f := range s {
+    strs[i] = f.Field
+    if f.Desc {
+      strs[i] = "-" + f.Field
+    }
+  }
+  return []byte(strings.Join(strs, ",")), nil
+}
+
+type FilterOp string
+
+const(
+  FilterEq FilterOp = ":"
+  FilterNe FilterOp = "!:"
+  FilterLt FilterOp = "<"
+  FilterLe FilterOp = "<="
+  FilterGt FilterOp = ">"
+  FilterGe FilterOp = ">="
+)
+
+// filterOps are in the order they are matched, so that "<=" is not taken
+// for "<".
+var filterOps = []FilterOp{FilterNe, FilterLe, FilterGe, FilterEq, FilterLt, FilterGt}
+
+// FilterTerm compares a field with a value. The value is left as a string,
+// for the backend to convert according to the field.
+type FilterTerm struct{
+  Field string
+  Op FilterOp
+  Value string
+}
+
+// FilterSpec is a field type parsed from a filter expression such as
+// "labels:go,published>2017-01-01", matching when every term matches. Field
+// names must be listed by the tag's fields flag, as for SortSpec.
+type FilterSpec []FilterTerm
+
+func (s FilterSpec) MarshalText() ([]byte, error) {
+  strs := make([]string, len(s))
+  for i, t := range s {
+    strs[i] = t.Field + string(t.Op) + t.Value
+  }
+  return []byte(strings.Join(strs, ",")), nil
+}
+
+var sortSpecType = reflect.TypeOf(SortSpec(nil))
+var filterSpecType = reflect.TypeOf(FilterSpec(nil))
+
+func isSpecType(t reflect.Type) bool {
+  return t == sortSpecType || t == filterSpecType
+}
+
+func checkSpecField(opts *tagOpts, optType string, field string) error {
+  for _, f := range opts.fields {
+    if f == field {
+      return nil
+    }
+  }
+  return &optsError{optType, opts.name, field}
+}
+
+func parseSort(opts *tagOpts, str string) (SortSpec, error) {
+  var spec SortSpec
+  for _, item := range strings.Split(str, ",") {
+    f := SortField{Field: item}
+    switch {
+      case strings.HasPrefix(item, "-"):
+        f = SortField{Field: item[1:], Desc: true}
+      case strings.HasPrefix(item, "+"):
+        f.Field = item[1:]
+    }
+    if len(f.Field) == 0 {
+      return nil, &optsError{"sort", opts.name, str}
+    }
+    if err := checkSpecField(opts, "sort field", f.Field); err != nil {
+      return nil, err
+    }
+    spec = append(spec, f)
+  }
+  return spec, nil
+}
+
+func parseFilter(opts *tagOpts, str string) (FilterSpec, error) {
+  var spec FilterSpec
+  for _, term := range strings.Split(str, ",") {
+    i := strings.IndexAny(term, ":!<>")
+    if i <= 0 {
+      return nil, &optsError{"filter", opts.name, term}
+    }
+    t := FilterTerm{Field: term[:i]}
+    for _, op := range filterOps {
+      if strings.HasPrefix(term[i:], string(op)) {
+        t.Op, t.Value = op, term[i + len(op):]
+        break
+      }
+    }
+    if len(t.Op) == 0 {
+      return nil, &optsError{"filter", opts.name, term}
+    }
+    if err := checkSpecField(opts, "filter field", t.Field); err != nil {
+      return nil, err
+    }
+    spec = append(spec, t)
+  }
+  return spec, nil
+}
+
+func setSpec(v reflect.Value, opts *tagOpts, str string) error {
+  if v.Type() == sortSpecType {
+    spec, err := parseSort(opts, str)
+    if err != nil {
+      return err
+    }
+    v.Set(reflect.ValueOf(spec))
+    return nil
+  }
+  spec, err := parseFilter(opts, str)
+  if err != nil {
+    return err
+  }
+  v.Set(reflect.ValueOf(spec))
+  return nil
+}
+
+// checkSpecTag checks that a SortSpec or FilterSpec field has a whitelist.
+func checkSpecTag(t reflect.Type, name string, opts *tagOpts) error {
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+  if isSpecType(t) && len(opts.fields) == 0 {
+    return fmt.Errorf("Field %s of type %s needs a fields flag", name, t)
+  }
+  return nil
+}
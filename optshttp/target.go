@@ -0,0 +1,60 @@
+package optshttp
+
+import (
+  "fmt"
+  "reflect"
+)
+
+type targetError struct{
+  t reflect.Type
+  reason string
+}
+
+func (e *targetError) Error() string {
+  if e.t == nil {
+    return "Invalid nil target: " + e.reason
+  }
+  return fmt.Sprintf("Invalid target %s: %s", e.t, e.reason)
+}
+
+// target returns the struct v points to. Nil pointers between v and the
+// struct are allocated, and interfaces are followed if they hold a
+// pointer.
+func target(v interface{}) (reflect.Value, error) {
+  rv := reflect.ValueOf(v)
+  if !rv.IsValid() {
+    return rv, &targetError{nil, "must be a pointer to a struct"}
+  }
+  if rv.Kind() != reflect.Ptr {
+    return rv, &targetError{rv.Type(), "must be a pointer to a struct"}
+  }
+  if rv.IsNil() {
+    return rv, &targetError{rv.Type(), "pointer is nil"}
+  }
+  t := rv.Type()
+  rv = rv.Elem()
+  for {
+    switch rv.Kind() {
+      case reflect.Struct:
+        return rv, nil
+      case reflect.Ptr:
+        if rv.IsNil() {
+          rv.Set(reflect.New(rv.Type().Elem()))
+        }
+        rv = rv.Elem()
+      case reflect.Interface:
+        if rv.IsNil() {
+          return rv, &targetError{t, "interface holds nil"}
+        }
+        rv = rv.Elem()
+        if rv.Kind() != reflect.Ptr {
+          return rv, &targetError{t, fmt.Sprintf("interface holds a %s, not a pointer", rv.Type())}
+        }
+        if rv.IsNil() {
+          return rv, &targetError{t, "interface holds a nil pointer"}
+        }
+      default:
+        return rv, &targetError{t, "must be a pointer to a struct"}
+    }
+  }
+}
@@ -0,0 +1,194 @@
+package optshttp_test
+
+import (
+  "github.com/istreeter/gotools/optshttp"
+  "flag"
+  "net/http"
+  "testing"
+  "time"
+)
+
+type targetParams struct {
+  Page int `form:"page" path:"page" header:"page" cookie:"page"`
+}
+
+type unexportedParams struct {
+  page int `form:"page"`
+}
+
+type inlinePtrParams struct {
+  Inner *targetParams `form:",inline"`
+}
+
+type notStructParams struct {
+  Page int `form:"page,prefix"`
+}
+
+type decimalStringParams struct {
+  Name string `form:"name,decimal=2"`
+}
+
+type precIntParams struct {
+  Count int `form:"count,prec=128"`
+}
+
+type unexportedOrderParams struct {
+  Since time.Time `form:"since" validate:"before=until"`
+  until time.Time
+}
+
+type unknownOrderParams struct {
+  Since time.Time `form:"since" validate:"before=Until"`
+}
+
+type notTimeOrderParams struct {
+  Since time.Time `form:"since" validate:"before=Until"`
+  Until int       `form:"until"`
+}
+
+type headerPrefixParams struct {
+  Author struct {
+    Name string `header:"name"`
+  } `header:"X-Author,prefix"`
+}
+
+type badMinParams struct {
+  Page int `form:"page" validate:"min=abc"`
+}
+
+type minLenIntParams struct {
+  Page int `form:"page" validate:"minlen=2"`
+}
+
+type badMaxLenParams struct {
+  Name string `form:"name" validate:"maxlen=-1"`
+}
+
+type minOnStringParams struct {
+  Name string `form:"name" validate:"min=1"`
+}
+
+func badTargets() map[string]interface{} {
+  var nilIface interface{}
+  var valueIface interface{} = targetParams{}
+  var nilPtrIface interface{} = (*targetParams)(nil)
+  n := 1
+  return map[string]interface{}{
+    "nil": nil,
+    "int": 1,
+    "struct value": targetParams{},
+    "nil pointer": (*targetParams)(nil),
+    "nil generated": (*genParams)(nil),
+    "pointer to int": &n,
+    "pointer to slice": &[]string{},
+    "pointer to map": &map[string]string{},
+    "pointer to nil interface": &nilIface,
+    "interface holding value": &valueIface,
+    "interface holding nil pointer": &nilPtrIface,
+    "unexported field": &unexportedParams{},
+    "inline pointer": &inlinePtrParams{},
+    "prefix on int": &notStructParams{},
+    "before unexported field": &unexportedOrderParams{},
+    "before unknown field": &unknownOrderParams{},
+    "before field not a time": &notTimeOrderParams{},
+    "decimal on string": &decimalStringParams{},
+    "prec on int": &precIntParams{},
+    "prefix on header": &headerPrefixParams{},
+    "min not an int": &badMinParams{},
+    "minlen on int": &minLenIntParams{},
+    "negative maxlen": &badMaxLenParams{},
+    "min on string": &minOnStringParams{},
+  }
+}
+
+func unmarshalFuncs() map[string]func(req *http.Request, v interface{}) error {
+  return map[string]func(req *http.Request, v interface{}) error{
+    "UnmarshalForm": optshttp.UnmarshalForm,
+    "UnmarshalFormStrict": optshttp.UnmarshalFormStrict,
+    "UnmarshalPath": optshttp.UnmarshalPath,
+    "UnmarshalHeader": optshttp.UnmarshalHeader,
+    "UnmarshalCookie": optshttp.UnmarshalCookie,
+    "Unmarshal": optshttp.Unmarshal,
+    "UnmarshalEnv": func(req *http.Request, v interface{}) error {
+      return optshttp.UnmarshalEnv(v)
+    },
+    "UnmarshalConfig": func(req *http.Request, v interface{}) error {
+      return optshttp.UnmarshalConfig(flag.NewFlagSet("test", flag.ContinueOnError), v)
+    },
+  }
+}
+
+func noPanic(t *testing.T, name string, f func() error) (err error) {
+  defer func() {
+    if r := recover(); r != nil {
+      t.Errorf("%s panicked: %v", name, r)
+    }
+  }()
+  return f()
+}
+
+func TestUnmarshalBadTargets(t *testing.T) {
+  for fname, f := range unmarshalFuncs() {
+    for tname, v := range badTargets() {
+      req, _ := http.NewRequest("GET", "http://example.com/?page=1&since=2017-01-01T00:00:00Z", nil)
+      name := fname + " into " + tname
+      if err := noPanic(t, name, func() error { return f(req, v) }); err == nil {
+        t.Errorf("%s returned no error", name)
+      }
+    }
+  }
+}
+
+func TestMarshalBadTargets(t *testing.T) {
+  for tname, v := range badTargets() {
+    noPanic(t, "MarshalForm of " + tname, func() error {
+      _, err := optshttp.MarshalForm(v)
+      return err
+    })
+    noPanic(t, "MarshalPath of " + tname, func() error {
+      _, err := optshttp.MarshalPath(v)
+      return err
+    })
+    noPanic(t, "RegisterFlags of " + tname, func() error {
+      return optshttp.RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), v)
+    })
+  }
+  for _, v := range []interface{}{nil, 1, &unexportedParams{}, &inlinePtrParams{}} {
+    if _, err := optshttp.MarshalForm(v); err == nil {
+      t.Errorf("MarshalForm of %T returned no error", v)
+    }
+  }
+}
+
+func TestUnmarshalIndirectTargets(t *testing.T) {
+  defer func(lookup optshttp.PathLookup) {
+    optshttp.DefaultPathLookup = lookup
+  }(optshttp.DefaultPathLookup)
+  optshttp.DefaultPathLookup = optshttp.PathLookupFunc(func(req *http.Request, name string) (string, bool) {
+    return "2", true
+  })
+
+  for fname, f := range unmarshalFuncs() {
+    if fname == "UnmarshalEnv" || fname == "UnmarshalConfig" {
+      continue
+    }
+    req, _ := http.NewRequest("GET", "http://example.com/?page=2", nil)
+    req.Header.Set("Page", "2")
+    req.AddCookie(&http.Cookie{Name: "page", Value: "2"})
+
+    var ptr *targetParams
+    if err := noPanic(t, fname, func() error { return f(req, &ptr) }); err != nil {
+      t.Errorf("%s into pointer to nil pointer: %v", fname, err)
+    } else if ptr == nil || ptr.Page != 2 {
+      t.Errorf("%s into pointer to nil pointer bound %+v", fname, ptr)
+    }
+
+    params := &targetParams{}
+    var iface interface{} = params
+    if err := noPanic(t, fname, func() error { return f(req, &iface) }); err != nil {
+      t.Errorf("%s into interface: %v", fname, err)
+    } else if params.Page != 2 {
+      t.Errorf("%s into interface bound %+v", fname, params)
+    }
+  }
+}
@@ -0,0 +1,83 @@
+package optshttp
+
+import (
+  "strconv"
+  "time"
+)
+
+const(
+  flagLayout = "layout="
+  flagUnix = "unix"
+  flagUnixMs = "unixms"
+  flagTZ = "tz="
+  flagTZParam = "tzparam="
+)
+
+// location returns the time zone for times without an explicit offset, which
+// is the tz= flag, or UTC.
+func (opts *tagOpts) location() *time.Location {
+  if opts.loc != nil {
+    return opts.loc
+  }
+  return time.UTC
+}
+
+// withRequestLocation returns a copy of opts whose time zone is named by the
+// tzparam= parameter of the request, if it has one.
+func withRequestLocation(opts *tagOpts, src *source) (*tagOpts, error) {
+  name, ok := firstValue(src.lookup(opts.tzParam), false)
+  if !ok {
+    return opts, nil
+  }
+  loc, err := time.LoadLocation(name)
+  if err != nil {
+    return opts, &optsError{"time zone", opts.tzParam, name}
+  }
+  withLoc := *opts
+  withLoc.loc = loc
+  return &withLoc, nil
+}
+
+func parseTime(opts *tagOpts, str string) (time.Time, error) {
+  switch {
+    case opts.unit == flagUnix:
+      secs, err := strconv.ParseInt(str, 10, 64)
+      if err != nil {
+        return time.Time{}, &optsError{"Unix time in seconds", opts.name, str}
+      }
+      return time.Unix(secs, 0).In(opts.location()), nil
+    case opts.unit == flagUnixMs:
+      ms, err := strconv.ParseInt(str, 10, 64)
+      if err != nil {
+        return time.Time{}, &optsError{"Unix time in milliseconds", opts.name, str}
+      }
+      return time.Unix(ms / 1000, ms % 1000 * int64(time.Millisecond)).In(opts.location()), nil
+    case len(opts.layout) > 0:
+      t, err := time.ParseInLocation(opts.layout, str, opts.location())
+      if err != nil {
+        return time.Time{}, &optsError{"time in format " + opts.layout, opts.name, str}
+      }
+      return t, nil
+  }
+  t := time.Time{}
+  if err := t.UnmarshalText([]byte(str)); err != nil {
+    return time.Time{}, &optsError{"time in RFC3339 format", opts.name, str}
+  }
+  if opts.loc != nil {
+    t = t.In(opts.loc)
+  }
+  return t, nil
+}
+
+// formatTime is the inverse of parseTime.
+func formatTime(opts *tagOpts, t time.Time) string {
+  switch {
+    case opts.unit == flagUnix:
+      return strconv.FormatInt(t.Unix(), 10)
+    case opts.unit == flagUnixMs:
+      return strconv.FormatInt(t.UnixNano() / int64(time.Millisecond), 10)
+    case len(opts.layout) > 0:
+      return t.In(opts.location()).Format(opts.layout)
+  }
+  return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,397 @@
+package optshttp
+
+import (
+  "fmt"
+  "math/big"
+  "reflect"
+  "regexp"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+)
+
+const(
+  flagValidate = "validate"
+  ruleMin = "min"
+  ruleMax = "max"
+  ruleMinLen = "minlen"
+  ruleMaxLen = "maxlen"
+  ruleOneOf = "oneof"
+  ruleRegex = "regex"
+  ruleBefore = "before"
+  ruleAfter = "after"
+)
+
+type constraintError struct{
+  optKey string
+  constraint string
+}
+
+func (e *constraintError) Error() string {
+  return fmt.Sprintf("Invalid %s: must %s", e.optKey, e.constraint)
+}
+
+type rule struct{
+  name string
+  arg string
+  field int
+  keys map[string]string
+  length int
+  bound interface{}
+}
+
+// parseValidate parses a tag such as "min=1,max=100". A regex rule must
+// come last, because everything after "regex=" is the pattern, commas
+// included.
+func parseValidate(tagStr string) ([]rule, error) {
+  var rules []rule
+  var regex *rule
+  if i := strings.Index(tagStr, ruleRegex + "="); i >= 0 && (i == 0 || tagStr[i-1] == ',') {
+    pattern := tagStr[i + len(ruleRegex) + 1:]
+    if _, err := compileRegex(pattern); err != nil {
+      return nil, fmt.Errorf("Invalid regex in validate tag %q: %v", tagStr, err)
+    }
+    regex = &rule{name: ruleRegex, arg: pattern}
+    tagStr = strings.TrimSuffix(tagStr[:i], ",")
+  }
+  if len(tagStr) > 0 {
+    for _, r := range strings.Split(tagStr, ",") {
+      kv := strings.SplitN(r, "=", 2)
+      if len(kv) != 2 {
+        return nil, fmt.Errorf("Invalid rule %q in validate tag", r)
+      }
+      switch kv[0] {
+        case ruleMin, ruleMax, ruleMinLen, ruleMaxLen, ruleOneOf, ruleBefore, ruleAfter:
+          rules = append(rules, rule{name: kv[0], arg: kv[1]})
+        default:
+          return nil, fmt.Errorf("Unknown rule %q in validate tag", kv[0])
+      }
+    }
+  }
+  if regex != nil {
+    rules = append(rules, *regex)
+  }
+  return rules, nil
+}
+
+// resolveOrder resolves the field named by each before and after rule of
+// field, which must be an exported time field of the same struct t, and
+// the parameter keys used to name it in messages.
+func resolveOrder(t reflect.Type, field reflect.StructField, rules []rule) error {
+  for i := range rules {
+    r := &rules[i]
+    if r.name != ruleBefore && r.name != ruleAfter {
+      continue
+    }
+    other, ok := t.FieldByName(r.arg)
+    if !ok || len(other.Index) != 1 {
+      return fmt.Errorf("Rule %s of field %s refers to unknown field %s", r.name, field.Name, r.arg)
+    }
+    if len(other.PkgPath) > 0 {
+      return fmt.Errorf("Rule %s of field %s refers to unexported field %s", r.name, field.Name, r.arg)
+    }
+    if valueType(field.Type) != timeType || valueType(other.Type) != timeType {
+      return fmt.Errorf("Rule %s of field %s applies only to times", r.name, field.Name)
+    }
+    r.field = other.Index[0]
+    r.keys = make(map[string]string)
+    for _, tagKey := range tagKeys {
+      if opts, err := parseTag(other.Tag.Get(tagKey)); err == nil && len(opts.name) > 0 {
+        r.keys[tagKey] = opts.name
+      }
+    }
+  }
+  return nil
+}
+
+// valueType returns the type of the value of t, through pointers and
+// Optional.
+func valueType(t reflect.Type) reflect.Type {
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+  if isOptional(t) {
+    value, _ := t.FieldByName("Value")
+    return valueType(value.Type)
+  }
+  return t
+}
+
+// compileRules parses the arguments of the length and bound rules of field
+// for its type, so that a bad validate tag fails when the type is compiled
+// rather than when a value is checked.
+func compileRules(field reflect.StructField, rules []rule) error {
+  t := valueType(field.Type)
+  for i := range rules {
+    r := &rules[i]
+    switch r.name {
+      case ruleMinLen, ruleMaxLen:
+        n, err := strconv.Atoi(r.arg)
+        if err != nil || n < 0 {
+          return fmt.Errorf("Invalid %s %q for field %s", r.name, r.arg, field.Name)
+        }
+        switch t.Kind() {
+          case reflect.String, reflect.Slice, reflect.Array:
+          default:
+            return fmt.Errorf("Rule %s of field %s does not apply to %s", r.name, field.Name, t)
+        }
+        r.length = n
+      case ruleMin, ruleMax:
+        elem := t
+        if elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array {
+          elem = elem.Elem()
+        }
+        for elem.Kind() == reflect.Ptr {
+          elem = elem.Elem()
+        }
+        bound, err := parseBound(elem, r.arg)
+        if err != nil {
+          return fmt.Errorf("Invalid %s %q for field %s: %v", r.name, r.arg, field.Name, err)
+        }
+        r.bound = bound
+    }
+  }
+  return nil
+}
+
+// otherKey names the other field of a before or after rule by its key in
+// the source the rule's field was bound from.
+func (r *rule) otherKey(f *boundField) string {
+  if key, ok := r.keys[f.source]; ok {
+    return f.prefix + key
+  }
+  for _, tagKey := range tagKeys {
+    if key, ok := r.keys[tagKey]; ok {
+      return key
+    }
+  }
+  return r.arg
+}
+
+var regexCache = struct{
+  sync.Mutex
+  m map[string]*regexp.Regexp
+}{m: make(map[string]*regexp.Regexp)}
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+  regexCache.Lock()
+  defer regexCache.Unlock()
+  if re, ok := regexCache.m[pattern]; ok {
+    return re, nil
+  }
+  re, err := regexp.Compile(pattern)
+  if err != nil {
+    return nil, err
+  }
+  regexCache.m[pattern] = re
+  return re, nil
+}
+
+// boundField is a field which was set while unmarshalling, and so is
+// validated afterwards.
+type boundField struct{
+  v reflect.Value
+  parent reflect.Value
+  source string
+  prefix string
+  key string
+  rules []rule
+}
+
+func validateFields(fields []boundField, verr *ValidationError) error {
+  for i := range fields {
+    f := &fields[i]
+    for _, r := range f.rules {
+      if err := checkRule(f, r); err != nil {
+        if _, ok := err.(*constraintError); !ok {
+          return err
+        }
+        verr.add(f.source, f.key, err)
+        break
+      }
+    }
+  }
+  return nil
+}
+
+func indirect(v reflect.Value) (reflect.Value, bool) {
+  for v.Kind() == reflect.Ptr {
+    if v.IsNil() {
+      return v, false
+    }
+    v = v.Elem()
+  }
+  return v, true
+}
+
+func checkRule(f *boundField, r rule) error {
+  v, ok := indirect(f.v)
+  if !ok {
+    return nil
+  }
+  switch r.name {
+    case ruleMinLen, ruleMaxLen:
+      bound := r.length
+      var n int
+      switch v.Kind() {
+        case reflect.String:
+          n = len([]rune(v.String()))
+        case reflect.Slice, reflect.Array:
+          n = v.Len()
+        default:
+          return fmt.Errorf("Rule %s does not apply to %s", r.name, v.Type())
+      }
+      if r.name == ruleMinLen && n < bound {
+        return &constraintError{f.key, fmt.Sprintf("have length at least %d", bound)}
+      }
+      if r.name == ruleMaxLen && n > bound {
+        return &constraintError{f.key, fmt.Sprintf("have length at most %d", bound)}
+      }
+      return nil
+    case ruleBefore, ruleAfter:
+      return checkOrder(f, r, v, f.parent.Field(r.field))
+  }
+  if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+    for i := 0; i < v.Len(); i++ {
+      if err := checkValue(f.key, r, v.Index(i)); err != nil {
+        return err
+      }
+    }
+    return nil
+  }
+  return checkValue(f.key, r, v)
+}
+
+func checkOrder(f *boundField, r rule, v reflect.Value, other reflect.Value) error {
+  other, ok := indirect(unwrapOptional(other))
+  if !ok {
+    return nil
+  }
+  t, o := v.Interface().(time.Time), other.Interface().(time.Time)
+  if o.IsZero() {
+    return nil
+  }
+  if r.name == ruleBefore && !t.Before(o) {
+    return &constraintError{f.key, "be before " + r.otherKey(f)}
+  }
+  if r.name == ruleAfter && !t.After(o) {
+    return &constraintError{f.key, "be after " + r.otherKey(f)}
+  }
+  return nil
+}
+
+func checkValue(key string, r rule, v reflect.Value) error {
+  v, ok := indirect(v)
+  if !ok {
+    return nil
+  }
+  switch r.name {
+    case ruleOneOf:
+      options := strings.Fields(r.arg)
+      str := valueString(v)
+      for _, o := range options {
+        if o == str {
+          return nil
+        }
+      }
+      return &constraintError{key, fmt.Sprintf("be one of %v", options)}
+    case ruleRegex:
+      re, err := compileRegex(r.arg)
+      if err != nil {
+        return err
+      }
+      if !re.MatchString(valueString(v)) {
+        return &constraintError{key, "match " + r.arg}
+      }
+      return nil
+  }
+
+  cmp, err := compareBound(v, r.bound)
+  if err != nil {
+    return fmt.Errorf("Invalid %s %q for %s: %v", r.name, r.arg, key, err)
+  }
+  if r.name == ruleMin && cmp < 0 {
+    return &constraintError{key, "be at least " + r.arg}
+  }
+  if r.name == ruleMax && cmp > 0 {
+    return &constraintError{key, "be at most " + r.arg}
+  }
+  return nil
+}
+
+func valueString(v reflect.Value) string {
+  switch v.Kind() {
+    case reflect.String:
+      return v.String()
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+      if v.Type() == durationType || v.Type() == monthType {
+        break
+      }
+      return strconv.FormatInt(v.Int(), 10)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      return strconv.FormatUint(v.Uint(), 10)
+  }
+  return fmt.Sprint(v.Interface())
+}
+
+// parseBound parses the argument of a min or max rule for a value of type t.
+func parseBound(t reflect.Type, bound string) (interface{}, error) {
+  switch t.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+      if t == durationType {
+        d, err := time.ParseDuration(bound)
+        return int64(d), err
+      }
+      return strconv.ParseInt(bound, 10, 64)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      return strconv.ParseUint(bound, 10, 64)
+    case reflect.Float32, reflect.Float64:
+      return strconv.ParseFloat(bound, 64)
+    case reflect.Struct:
+      if isBigType(t) {
+        b, ok := new(big.Rat).SetString(bound)
+        if !ok {
+          return nil, fmt.Errorf("not a number")
+        }
+        return b, nil
+      }
+      if t == timeType {
+        return time.Parse(time.RFC3339, bound)
+      }
+  }
+  return nil, fmt.Errorf("min and max do not apply to %s", t)
+}
+
+// compareBound returns -1, 0 or 1 as v is less than, equal to or greater
+// than bound, as parsed by parseBound for the type of v.
+func compareBound(v reflect.Value, bound interface{}) (int, error) {
+  switch b := bound.(type) {
+    case int64:
+      return order(v.Int() < b, v.Int() > b), nil
+    case uint64:
+      return order(v.Uint() < b, v.Uint() > b), nil
+    case float64:
+      return order(v.Float() < b, v.Float() > b), nil
+    case *big.Rat:
+      r, ok := bigRat(v)
+      if !ok {
+        return 0, fmt.Errorf("Invalid bound %s for %s", b, v.Type())
+      }
+      return r.Cmp(b), nil
+    case time.Time:
+      t := v.Interface().(time.Time)
+      return order(t.Before(b), t.After(b)), nil
+  }
+  return 0, fmt.Errorf("min and max do not apply to %s", v.Type())
+}
+
+func order(less bool, greater bool) int {
+  switch {
+    case less:
+      return -1
+    case greater:
+      return 1
+  }
+  return 0
+}
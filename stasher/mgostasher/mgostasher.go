@@ -2,6 +2,7 @@ package mgostasher
 
 import (
   "context"
+  "fmt"
   "time"
   "google.golang.org/api/blogger/v3"
   "github.com/dghubble/go-twitter/twitter"
@@ -217,3 +218,49 @@ func(m *TwitterStasher) StashTweet(ctx context.Context, tweet *twitter.Tweet, oe
   _, err := m.TweetCollection.Upsert(bson.M{"tweet.id": tweet.ID}, &dbTweet);
   if err != nil { panic(err) }
 }
+
+// checkSession copies session with its timeouts bounded by the deadline of
+// ctx, so that a check which has timed out does not stay blocked on the
+// database.
+func checkSession(ctx context.Context, session *mgo.Session) *mgo.Session {
+  s := session.Copy()
+  if deadline, ok := ctx.Deadline(); ok {
+    timeout := time.Until(deadline)
+    if timeout <= 0 {
+      timeout = time.Millisecond
+    }
+    s.SetSocketTimeout(timeout)
+    s.SetSyncTimeout(timeout)
+  }
+  return s
+}
+
+func PingCheck(session *mgo.Session) func(context.Context) error {
+  return func(ctx context.Context) error {
+    s := checkSession(ctx, session)
+    defer s.Close()
+    return s.Ping()
+  }
+}
+
+func(m *BlogStasher) SyncAgeCheck(blogId string, maxAge time.Duration) func(context.Context) error {
+  return func(ctx context.Context) error {
+    s := checkSession(ctx, m.BlogCollection.Database.Session)
+    defer s.Close()
+    dbBlog := new(Blog)
+    err := m.BlogCollection.With(s).Find(bson.M{"blog.id": blogId}).Select(bson.M{"postListUpdated": 1}).One(dbBlog)
+    if err != nil {
+      if err == mgo.ErrNotFound {
+        return fmt.Errorf("Blog %v has never been synced", blogId)
+      }
+      return err
+    }
+    if dbBlog.PostListUpdated == nil {
+      return fmt.Errorf("Blog %v has never been synced", blogId)
+    }
+    if age := time.Since(*dbBlog.PostListUpdated); age > maxAge {
+      return fmt.Errorf("Blog %v last synced %v ago", blogId, age)
+    }
+    return nil
+  }
+}
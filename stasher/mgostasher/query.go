@@ -0,0 +1,83 @@
+package mgostasher
+
+import (
+  "fmt"
+  "time"
+  "github.com/istreeter/gotools/optshttp"
+  "gopkg.in/mgo.v2/bson"
+)
+
+var filterOps = map[optshttp.FilterOp]string{
+  optshttp.FilterNe: "$ne",
+  optshttp.FilterLt: "$lt",
+  optshttp.FilterLe: "$lte",
+  optshttp.FilterGt: "$gt",
+  optshttp.FilterGe: "$gte",
+}
+
+// Field is the document field a spec field name is translated to, such as
+// "blogPost.published" for "published". Parse converts filter values to the
+// type of the document field, so that a time is not compared as a string;
+// a nil Parse leaves values as strings.
+type Field struct{
+  Name string
+  Parse optshttp.ParseFunc
+}
+
+// ParseTime parses an RFC 3339 time or a date such as "2017-01-01", for
+// the Parse of a time.Time field such as BlogPost.Updated.
+func ParseTime(s string) (interface{}, error) {
+  if t, err := time.Parse(time.RFC3339, s); err == nil {
+    return t, nil
+  }
+  return time.Parse("2006-01-02", s)
+}
+
+// FilterQuery translates a FilterSpec to a query, with spec field names
+// mapped by fields. Names not in fields are used as they are, with string
+// values.
+func FilterQuery(spec optshttp.FilterSpec, fields map[string]Field) (bson.M, error) {
+  var terms []bson.M
+  for _, t := range spec {
+    field := docField(t.Field, fields)
+    var value interface{} = t.Value
+    if parse := fields[t.Field].Parse; parse != nil {
+      var err error
+      if value, err = parse(t.Value); err != nil {
+        return nil, fmt.Errorf("Invalid value %s for filter field %s", t.Value, t.Field)
+      }
+    }
+    if t.Op == optshttp.FilterEq {
+      terms = append(terms, bson.M{field: value})
+    } else {
+      terms = append(terms, bson.M{field: bson.M{filterOps[t.Op]: value}})
+    }
+  }
+  switch len(terms) {
+    case 0:
+      return bson.M{}, nil
+    case 1:
+      return terms[0], nil
+  }
+  return bson.M{"$and": terms}, nil
+}
+
+// SortFields translates a SortSpec to the arguments of mgo.Query.Sort, with
+// fields mapped as for FilterQuery.
+func SortFields(spec optshttp.SortSpec, fields map[string]Field) []string {
+  sort := make([]string, len(spec))
+  for i, f := range spec {
+    sort[i] = docField(f.Field, fields)
+    if f.Desc {
+      sort[i] = "-" + sort[i]
+    }
+  }
+  return sort
+}
+
+func docField(name string, fields map[string]Field) string {
+  if field, ok := fields[name]; ok && len(field.Name) > 0 {
+    return field.Name
+  }
+  return name
+}
@@ -0,0 +1,70 @@
+package mgostasher
+
+import (
+  "reflect"
+  "strconv"
+  "testing"
+  "time"
+  "github.com/istreeter/gotools/optshttp"
+  "gopkg.in/mgo.v2/bson"
+)
+
+var queryFields = map[string]Field{
+  "published": {Name: "blogPost.published", Parse: ParseTime},
+  "count": {Parse: func(s string) (interface{}, error) { return strconv.Atoi(s) }},
+  "labels": {Name: "blogPost.labels"},
+}
+
+func term(field string, op optshttp.FilterOp, value string) optshttp.FilterTerm {
+  return optshttp.FilterTerm{Field: field, Op: op, Value: value}
+}
+
+func TestFilterQuery(t *testing.T) {
+  date := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+  tests := []struct{
+    name string
+    spec optshttp.FilterSpec
+    want bson.M
+  }{
+    {"empty", nil, bson.M{}},
+    {"eq", optshttp.FilterSpec{term("labels", optshttp.FilterEq, "go")}, bson.M{"blogPost.labels": "go"}},
+    {"ne", optshttp.FilterSpec{term("labels", optshttp.FilterNe, "go")}, bson.M{"blogPost.labels": bson.M{"$ne": "go"}}},
+    {"lt", optshttp.FilterSpec{term("count", optshttp.FilterLt, "3")}, bson.M{"count": bson.M{"$lt": 3}}},
+    {"le", optshttp.FilterSpec{term("count", optshttp.FilterLe, "3")}, bson.M{"count": bson.M{"$lte": 3}}},
+    {"gt", optshttp.FilterSpec{term("published", optshttp.FilterGt, "2017-01-01")}, bson.M{"blogPost.published": bson.M{"$gt": date}}},
+    {"ge", optshttp.FilterSpec{term("published", optshttp.FilterGe, "2017-01-01T00:00:00Z")}, bson.M{"blogPost.published": bson.M{"$gte": date}}},
+    {"unmapped", optshttp.FilterSpec{term("title", optshttp.FilterEq, "3")}, bson.M{"title": "3"}},
+    {"and", optshttp.FilterSpec{term("labels", optshttp.FilterEq, "go"), term("labels", optshttp.FilterEq, "http")}, bson.M{"$and": []bson.M{
+      {"blogPost.labels": "go"},
+      {"blogPost.labels": "http"},
+    }}},
+  }
+  for _, test := range tests {
+    got, err := FilterQuery(test.spec, queryFields)
+    if err != nil {
+      t.Errorf("%s: %v", test.name, err)
+      continue
+    }
+    if !reflect.DeepEqual(got, test.want) {
+      t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+    }
+  }
+}
+
+func TestFilterQueryInvalidValue(t *testing.T) {
+  for _, bad := range []optshttp.FilterTerm{term("published", optshttp.FilterGt, "yesterday"), term("count", optshttp.FilterEq, "many")} {
+    _, err := FilterQuery(optshttp.FilterSpec{bad}, queryFields)
+    want := "Invalid value " + bad.Value + " for filter field " + bad.Field
+    if err == nil || err.Error() != want {
+      t.Errorf("%s: got error %v, want %s", bad.Field, err, want)
+    }
+  }
+}
+
+func TestSortFields(t *testing.T) {
+  got := SortFields(optshttp.SortSpec{{Field: "published", Desc: true}, {Field: "title"}}, queryFields)
+  want := []string{"-blogPost.published", "title"}
+  if !reflect.DeepEqual(got, want) {
+    t.Errorf("got %v, want %v", got, want)
+  }
+}
@@ -49,6 +49,19 @@ func (rw *responseWriter) Write(content []byte) (int, error) {
   return rw.syncer.rw.Write(content)
 }
 
+func (rw *responseWriter) Flush() {
+  rw.claimSyncer()
+  if !rw.mine {
+    return
+  }
+  if (!rw.headerWritten) {
+    rw.writeHeader(http.StatusOK)
+  }
+  if f, ok := rw.syncer.rw.(http.Flusher); ok {
+    f.Flush()
+  }
+}
+
 // private
 
 func (rw *responseWriter) claimSyncer() {